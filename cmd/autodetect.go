@@ -2,37 +2,56 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"compost/internal/comment"
 	"compost/internal/detect"
 )
 
-// autodetectCmdHandler processes the flags and args for the autodetect commands
-// and returns the comment handler for posting/retrieving comments on the detected platform
-func autodetectCmdHandler(ctx context.Context, cmd *cobra.Command, args []string) (*comment.CommentHandler, error) {
+// autodetectOptions processes the flags for the autodetect commands
+// and returns the options for detecting the environment
+func autodetectOptions(cmd *cobra.Command) (detect.DetectOptions, error) {
 	platformVal, _ := cmd.Flags().GetString("platform")
 	platform, err := processPlatform(platformVal, true)
 	if err != nil {
-		return nil, err
+		return detect.DetectOptions{}, err
 	}
 
 	targetTypeVal, _ := cmd.Flags().GetString("target-type")
 	targetType, err := processTargetType(targetTypeVal, true)
+	if err != nil {
+		return detect.DetectOptions{}, err
+	}
+
+	bitbucketTokenEnvVar, _ := cmd.Flags().GetString("bitbucket-token-env-var")
+	detectors, _ := cmd.Flags().GetStringSlice("detector")
+
+	return detect.DetectOptions{
+		Platform:             platform,
+		TargetType:           targetType,
+		BitbucketTokenEnvVar: bitbucketTokenEnvVar,
+		Detectors:            detectors,
+	}, nil
+}
+
+// autodetectCmdHandler processes the flags and args for the autodetect commands
+// and returns the comment handler for posting/retrieving comments on the detected platform
+func autodetectCmdHandler(ctx context.Context, cmd *cobra.Command, args []string) (*comment.CommentHandler, error) {
+	opts, err := autodetectOptions(cmd)
 	if err != nil {
 		return nil, err
 	}
 
-	detectResult, err := detect.DetectEnvironment(ctx, detect.DetectOptions{
-		Platform:   platform,
-		TargetType: targetType,
-	})
+	detectResult, err := detect.DetectEnvironment(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return cmdHandler(
+	handler, err := cmdHandler(
 		ctx,
 		cmd,
 		detectResult.Platform,
@@ -41,6 +60,16 @@ func autodetectCmdHandler(ctx context.Context, cmd *cobra.Command, args []string
 		detectResult.TargetRef,
 		detectResult.Extra,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if detectResult.CommitSHA != "" {
+		handler.Metadata.CommitSHA = detectResult.CommitSHA
+	}
+	handler.Metadata.PipelineURL = detectResult.PipelineURL
+
+	return handler, nil
 }
 
 // autodetectCmd represents the autodetect command
@@ -57,24 +86,46 @@ var autodetectCmd = &cobra.Command{
   • Delete the previous posted comments and post a new comment:
       $ compost autodetect delete-and-new --body="my new comment"
 
-  • Hide the previous posted comments and post a new comment (GitHub only):
-      $ compost autodetect hide-and-new --body="my new comment"`,
+  • Hide the previous posted comments and post a new comment (GitHub, GitLab and Azure Repos only):
+      $ compost autodetect hide-and-new --body="my new comment"
+
+  • Delete the previous posted comments, keeping the latest one:
+      $ compost autodetect delete --keep-latest=1
+
+  • Hide the previous posted comments created more than a day ago (GitHub, GitLab and Azure Repos only):
+      $ compost autodetect hide --older-than=24h`,
 }
 
 // autodetectUpdateCmd represents the autodetect update command
 var autodetectUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update a comment on the pull/merge request or commit",
-	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.UpdateComment(ctx, body)
 	}),
 }
 
+// autodetectUpdateWithHistoryCmd represents the autodetect update-with-history command
+var autodetectUpdateWithHistoryCmd = &cobra.Command{
+	Use:   "update-with-history",
+	Short: "Update a comment on the pull/merge request or commit, keeping the previous versions collapsed below it",
+	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateCommentWithHistory(ctx, body)
+	}),
+}
+
+// autodetectUpdateSectionCmd represents the autodetect update-section command
+var autodetectUpdateSectionCmd = &cobra.Command{
+	Use:   "update-section",
+	Short: "Update a section of a comment on the pull/merge request or commit, keeping the other sections unchanged",
+	RunE:  updateSectionRunE(autodetectCmdHandler),
+}
+
 // autodetectNewCmd represents the autodetect new command
 var autodetectNewCmd = &cobra.Command{
 	Use:   "new",
 	Short: "Create a new comment on the pull/merge request or commit",
-	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.NewComment(ctx, body)
 	}),
 }
@@ -83,7 +134,7 @@ var autodetectNewCmd = &cobra.Command{
 var autodetectHideAndNewCmd = &cobra.Command{
 	Use:   "hide-and-new",
 	Short: "Hide existing comments and create a new comment on the pull/merge request or commit",
-	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.HideAndNewComment(ctx, body)
 	}),
 }
@@ -92,11 +143,29 @@ var autodetectHideAndNewCmd = &cobra.Command{
 var autodetectDeleteAndNewCmd = &cobra.Command{
 	Use:   "delete-and-new",
 	Short: "Delete existing comments and create a new comment on the pull/merge request or commit",
-	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.DeleteAndNewComment(ctx, body)
 	}),
 }
 
+// autodetectDeleteCmd represents the autodetect delete command
+var autodetectDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete existing comments on the pull/merge request or commit",
+	RunE: cleanupCommentsRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, opts comment.CleanupOptions) (*comment.Result, error) {
+		return handler.DeleteComments(ctx, opts)
+	}),
+}
+
+// autodetectHideCmd represents the autodetect hide command
+var autodetectHideCmd = &cobra.Command{
+	Use:   "hide",
+	Short: "Hide existing comments on the pull/merge request or commit",
+	RunE: cleanupCommentsRunE(autodetectCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, opts comment.CleanupOptions) (*comment.Result, error) {
+		return handler.HideComments(ctx, opts)
+	}),
+}
+
 // autodetectLatestCmd represents the autodetect latest command
 var autodetectLatestCmd = &cobra.Command{
 	Use:   "latest",
@@ -106,22 +175,121 @@ var autodetectLatestCmd = &cobra.Command{
 	}),
 }
 
+// autodetectListCmd represents the autodetect list command
+var autodetectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the matching comments on the pull/merge request or commit",
+	RunE:  listCommentsRunE(autodetectCmdHandler),
+}
+
+// autodetectExplainCmd represents the autodetect explain command
+var autodetectExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explain which environment variables each detector found and which detector would be used",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		opts, err := autodetectOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		explanations, err := detect.ExplainEnvironment(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		printDetectorExplanations(cmd, explanations)
+
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(autodetectCmd)
 
 	autodetectCmd.PersistentFlags().String("tag", "", "Customize the embedded tag that is used for detecting comments posted by Compost")
-	autodetectCmd.PersistentFlags().String("platform", "", "Limit the auto-detection to a specific platform: github, gitlab")
+	autodetectCmd.PersistentFlags().StringToString("meta", map[string]string{}, "Add a key=value pair to the metadata embedded in the comment, can be repeated")
+	autodetectCmd.PersistentFlags().Bool("dry-run", false, "Find the comments but only report what would be posted, updated, deleted or hidden")
+	autodetectCmd.PersistentFlags().String("author", "", "Only match comments written by this user, use 'self' for the user the token belongs to (GitHub and GitLab only)")
+	autodetectCmd.PersistentFlags().String("platform", "", "Limit the auto-detection to a specific platform: github, gitlab, bitbucket, bitbucket-server, azure-repos")
 	autodetectCmd.PersistentFlags().String("target-type", "", "Limit the auto-detection to pull/merge requests or commits: pull-request (pr), merge-request (mr), commit")
+	autodetectCmd.PersistentFlags().String("bitbucket-token-env-var", "BITBUCKET_TOKEN", "Environment variable containing the Bitbucket access token or app password")
+	autodetectCmd.PersistentFlags().StringSlice("detector", []string{}, fmt.Sprintf("Only use the given detectors, in order of precedence: %s", strings.Join(detect.DetectorNames(), ", ")))
 
 	autodetectCmd.AddCommand(autodetectUpdateCmd)
+	autodetectCmd.AddCommand(autodetectUpdateWithHistoryCmd)
+	autodetectCmd.AddCommand(autodetectUpdateSectionCmd)
 	autodetectCmd.AddCommand(autodetectNewCmd)
 	autodetectCmd.AddCommand(autodetectHideAndNewCmd)
 	autodetectCmd.AddCommand(autodetectDeleteAndNewCmd)
+	autodetectCmd.AddCommand(autodetectDeleteCmd)
+	autodetectCmd.AddCommand(autodetectHideCmd)
 	autodetectCmd.AddCommand(autodetectLatestCmd)
+	autodetectCmd.AddCommand(autodetectListCmd)
+	autodetectCmd.AddCommand(autodetectExplainCmd)
+
+	// Add the body flags to any commands that post comments
+	for _, cmd := range []*cobra.Command{autodetectUpdateCmd, autodetectUpdateWithHistoryCmd, autodetectUpdateSectionCmd, autodetectNewCmd, autodetectHideAndNewCmd, autodetectDeleteAndNewCmd} {
+		addBodyFlags(cmd)
+	}
+
+	addHistoryFlags(autodetectUpdateWithHistoryCmd)
 
-	// Add the body and body-file flags to any commands that post comments
-	for _, cmd := range []*cobra.Command{autodetectUpdateCmd, autodetectNewCmd, autodetectHideAndNewCmd, autodetectDeleteAndNewCmd} {
-		cmd.Flags().String("body", "", "Body of comment to post, mutually exclusive with body-file")
-		cmd.Flags().String("body-file", "", "File containing body of comment to post, mutually exclusive with body")
+	autodetectUpdateSectionCmd.Flags().String("section", "", "ID of the section of the comment to update")
+
+	// Add the cleanup flags to any commands that delete or hide comments without posting
+	for _, cmd := range []*cobra.Command{autodetectDeleteCmd, autodetectHideCmd} {
+		addCleanupFlags(cmd)
+	}
+}
+
+// printDetectorExplanations outputs the outcome of each detector and which
+// detector's result would be used.
+func printDetectorExplanations(cmd *cobra.Command, explanations []detect.DetectorExplanation) {
+	var selected *detect.DetectorExplanation
+
+	for i, e := range explanations {
+		if e.Selected {
+			selected = &explanations[i]
+		}
+
+		if e.Skipped {
+			cmd.Printf("%s %s (%s)\n", color.HiBlackString("-"), e.DisplayName, e.Name)
+			cmd.Printf("    skipped, supported platforms: %s\n\n", strings.Join(e.SupportedPlatforms, ", "))
+			continue
+		}
+
+		if e.Result != nil {
+			cmd.Printf("%s %s (%s)\n", color.GreenString("✔"), e.DisplayName, e.Name)
+		} else {
+			cmd.Printf("%s %s (%s)\n", color.RedString("✘"), e.DisplayName, e.Name)
+		}
+
+		for _, envVar := range e.EnvVars {
+			if envVar.Found {
+				cmd.Printf("    %s %s=%s\n", color.GreenString("found"), envVar.Name, envVar.Value)
+			} else {
+				cmd.Printf("    %s %s\n", color.RedString("missed"), envVar.Name)
+			}
+		}
+
+		if e.Result != nil {
+			cmd.Printf("    detected %s\n\n", formatDetectResult(*e.Result))
+		} else {
+			cmd.Printf("    not detected: %s\n\n", e.Err)
+		}
+	}
+
+	if selected == nil {
+		cmd.Println("No environment detected")
+		return
 	}
+
+	cmd.Printf("Using %s (%s): %s\n", selected.DisplayName, selected.Name, formatDetectResult(*selected.Result))
+}
+
+// formatDetectResult formats the detect result for output.
+func formatDetectResult(r detect.DetectResult) string {
+	return fmt.Sprintf("platform: %s, project: %s, target type: %s, target ref: %s", r.Platform, r.Project, r.TargetType, r.TargetRef)
 }
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"compost/internal/comment"
+)
+
+// azureReposCmdHandler processes common args and flags for all Azure Repos commands
+// and returns the comment handler for posting/retrieving Azure Repos comments
+func azureReposCmdHandler(ctx context.Context, cmd *cobra.Command, args []string) (*comment.CommentHandler, error) {
+	project, targetType, targetRef, err := processArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _ := cmd.Flags().GetString("azure-repos-token")
+
+	extra := comment.AzureReposExtra{
+		Token: token,
+	}
+
+	return cmdHandler(ctx, cmd, "azure-repos", project, targetType, targetRef, extra)
+}
+
+// azureReposCmd represents the azure-repos command
+var azureReposCmd = &cobra.Command{
+	Use:   "azure-repos",
+	Short: "Post a comment to an Azure Repos pull request",
+	Example: `
+  • Update a comment on a pull request:
+      $ compost azure-repos update https://dev.azure.com/infracost/compost/_git/compost-example pull-request 3 --body="my comment"
+
+  • Hide the previous comments and post a new comment on a pull request:
+      $ compost azure-repos hide-and-new https://dev.azure.com/infracost/compost/_git/compost-example pull-request 3 --body="my comment"`,
+}
+
+// azureReposUpdateCmd represents the azure-repos update command
+var azureReposUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a comment on an Azure Repos pull request",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(azureReposCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateComment(ctx, body)
+	}),
+}
+
+// azureReposUpdateWithHistoryCmd represents the azure-repos update-with-history command
+var azureReposUpdateWithHistoryCmd = &cobra.Command{
+	Use:   "update-with-history",
+	Short: "Update a comment on an Azure Repos pull request, keeping the previous versions collapsed below it",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(azureReposCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateCommentWithHistory(ctx, body)
+	}),
+}
+
+// azureReposUpdateSectionCmd represents the azure-repos update-section command
+var azureReposUpdateSectionCmd = &cobra.Command{
+	Use:   "update-section",
+	Short: "Update a section of a comment on an Azure Repos pull request, keeping the other sections unchanged",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  updateSectionRunE(azureReposCmdHandler),
+}
+
+// azureReposNewCmd represents the azure-repos new command
+var azureReposNewCmd = &cobra.Command{
+	Use:   "new",
+	Short: "Create a new comment on an Azure Repos pull request",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(azureReposCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.NewComment(ctx, body)
+	}),
+}
+
+// azureReposHideAndNewCmd represents the azure-repos hide-and-new command
+var azureReposHideAndNewCmd = &cobra.Command{
+	Use:   "hide-and-new",
+	Short: "Hide existing comments and create a new comment on an Azure Repos pull request",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(azureReposCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.HideAndNewComment(ctx, body)
+	}),
+}
+
+// azureReposDeleteAndNewCmd represents the azure-repos delete-and-new command
+var azureReposDeleteAndNewCmd = &cobra.Command{
+	Use:   "delete-and-new",
+	Short: "Delete existing comments and create a new comment on an Azure Repos pull request",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(azureReposCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.DeleteAndNewComment(ctx, body)
+	}),
+}
+
+// azureReposLatestCmd represents the azure-repos latest command
+var azureReposLatestCmd = &cobra.Command{
+	Use:   "latest",
+	Short: "Return the latest comment on an Azure Repos pull request",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: getCommentRunE(azureReposCmdHandler, func(ctx context.Context, handler *comment.CommentHandler) (comment.Comment, error) {
+		return handler.LatestMatchingComment(ctx)
+	}),
+}
+
+// azureReposListCmd represents the azure-repos list command
+var azureReposListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the matching comments on an Azure Repos pull request",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  listCommentsRunE(azureReposCmdHandler),
+}
+
+func init() {
+	rootCmd.AddCommand(azureReposCmd)
+
+	azureReposCmd.PersistentFlags().String("tag", "", "Customize the embedded tag that is used for detecting comments posted by Compost")
+	azureReposCmd.PersistentFlags().StringToString("meta", map[string]string{}, "Add a key=value pair to the metadata embedded in the comment, can be repeated")
+	azureReposCmd.PersistentFlags().Bool("dry-run", false, "Find the comments but only report what would be posted, updated, deleted or hidden")
+	azureReposCmd.PersistentFlags().String("azure-repos-token", "", "Azure DevOps personal access token or pipeline access token")
+
+	azureReposCmd.AddCommand(azureReposUpdateCmd)
+	azureReposCmd.AddCommand(azureReposUpdateWithHistoryCmd)
+	azureReposCmd.AddCommand(azureReposUpdateSectionCmd)
+	azureReposCmd.AddCommand(azureReposNewCmd)
+	azureReposCmd.AddCommand(azureReposHideAndNewCmd)
+	azureReposCmd.AddCommand(azureReposDeleteAndNewCmd)
+	azureReposCmd.AddCommand(azureReposLatestCmd)
+	azureReposCmd.AddCommand(azureReposListCmd)
+
+	// Add the body flags to any commands that post comments
+	for _, cmd := range []*cobra.Command{azureReposUpdateCmd, azureReposUpdateWithHistoryCmd, azureReposUpdateSectionCmd, azureReposNewCmd, azureReposHideAndNewCmd, azureReposDeleteAndNewCmd} {
+		addBodyFlags(cmd)
+	}
+
+	addHistoryFlags(azureReposUpdateWithHistoryCmd)
+
+	azureReposUpdateSectionCmd.Flags().String("section", "", "ID of the section of the comment to update")
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"compost/internal/comment"
+)
+
+// bitbucketCmdHandler processes common args and flags for all Bitbucket commands
+// and returns the comment handler for posting/retrieving Bitbucket comments
+func bitbucketCmdHandler(ctx context.Context, cmd *cobra.Command, args []string) (*comment.CommentHandler, error) {
+	project, targetType, targetRef, err := processArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL, _ := cmd.Flags().GetString("bitbucket-api-url")
+	token, _ := cmd.Flags().GetString("bitbucket-token")
+
+	extra := comment.BitbucketExtra{
+		APIURL: apiURL,
+		Token:  token,
+	}
+
+	return cmdHandler(ctx, cmd, "bitbucket", project, targetType, targetRef, extra)
+}
+
+// bitbucketCmd represents the bitbucket command
+var bitbucketCmd = &cobra.Command{
+	Use:   "bitbucket",
+	Short: "Post a comment to a Bitbucket pull request or commit",
+	Example: `
+  • Update a comment on a pull request:
+      $ compost bitbucket update infracost/compost-example pull-request 3 --body="my comment"
+
+  • Update a comment on a commit:
+      $ compost bitbucket update infracost/compost-example commit 2ca7182 --body="my comment"`,
+}
+
+// bitbucketUpdateCmd represents the bitbucket update command
+var bitbucketUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a comment on a Bitbucket pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateComment(ctx, body)
+	}),
+}
+
+// bitbucketUpdateWithHistoryCmd represents the bitbucket update-with-history command
+var bitbucketUpdateWithHistoryCmd = &cobra.Command{
+	Use:   "update-with-history",
+	Short: "Update a comment on a Bitbucket pull request or commit, keeping the previous versions collapsed below it",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateCommentWithHistory(ctx, body)
+	}),
+}
+
+// bitbucketUpdateSectionCmd represents the bitbucket update-section command
+var bitbucketUpdateSectionCmd = &cobra.Command{
+	Use:   "update-section",
+	Short: "Update a section of a comment on a Bitbucket pull request or commit, keeping the other sections unchanged",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  updateSectionRunE(bitbucketCmdHandler),
+}
+
+// bitbucketNewCmd represents the bitbucket new command
+var bitbucketNewCmd = &cobra.Command{
+	Use:   "new",
+	Short: "Create a new comment on a Bitbucket pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.NewComment(ctx, body)
+	}),
+}
+
+// bitbucketDeleteAndNewCmd represents the bitbucket delete-and-new command
+var bitbucketDeleteAndNewCmd = &cobra.Command{
+	Use:   "delete-and-new",
+	Short: "Delete existing comments and create a new comment on a Bitbucket pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.DeleteAndNewComment(ctx, body)
+	}),
+}
+
+// bitbucketLatestCmd represents the bitbucket latest command
+var bitbucketLatestCmd = &cobra.Command{
+	Use:   "latest",
+	Short: "Return the latest comment on a Bitbucket pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: getCommentRunE(bitbucketCmdHandler, func(ctx context.Context, handler *comment.CommentHandler) (comment.Comment, error) {
+		return handler.LatestMatchingComment(ctx)
+	}),
+}
+
+// bitbucketListCmd represents the bitbucket list command
+var bitbucketListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the matching comments on a Bitbucket pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  listCommentsRunE(bitbucketCmdHandler),
+}
+
+func init() {
+	rootCmd.AddCommand(bitbucketCmd)
+
+	bitbucketCmd.PersistentFlags().String("tag", "", "Customize the embedded tag that is used for detecting comments posted by Compost")
+	bitbucketCmd.PersistentFlags().StringToString("meta", map[string]string{}, "Add a key=value pair to the metadata embedded in the comment, can be repeated")
+	bitbucketCmd.PersistentFlags().Bool("dry-run", false, "Find the comments but only report what would be posted, updated, deleted or hidden")
+	bitbucketCmd.PersistentFlags().String("bitbucket-api-url", "", "Bitbucket API URL, defaults to https://api.bitbucket.org")
+	bitbucketCmd.PersistentFlags().String("bitbucket-token", "", "Bitbucket token, either an access token or username:app-password")
+
+	bitbucketCmd.AddCommand(bitbucketUpdateCmd)
+	bitbucketCmd.AddCommand(bitbucketUpdateWithHistoryCmd)
+	bitbucketCmd.AddCommand(bitbucketUpdateSectionCmd)
+	bitbucketCmd.AddCommand(bitbucketNewCmd)
+	bitbucketCmd.AddCommand(bitbucketDeleteAndNewCmd)
+	bitbucketCmd.AddCommand(bitbucketLatestCmd)
+	bitbucketCmd.AddCommand(bitbucketListCmd)
+
+	// Add the body flags to any commands that post comments
+	for _, cmd := range []*cobra.Command{bitbucketUpdateCmd, bitbucketUpdateWithHistoryCmd, bitbucketUpdateSectionCmd, bitbucketNewCmd, bitbucketDeleteAndNewCmd} {
+		addBodyFlags(cmd)
+	}
+
+	addHistoryFlags(bitbucketUpdateWithHistoryCmd)
+
+	bitbucketUpdateSectionCmd.Flags().String("section", "", "ID of the section of the comment to update")
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"compost/internal/comment"
+)
+
+// bitbucketServerCmdHandler processes common args and flags for all Bitbucket Server commands
+// and returns the comment handler for posting/retrieving Bitbucket Server comments
+func bitbucketServerCmdHandler(ctx context.Context, cmd *cobra.Command, args []string) (*comment.CommentHandler, error) {
+	project, targetType, targetRef, err := processArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, _ := cmd.Flags().GetString("bitbucket-server-url")
+	token, _ := cmd.Flags().GetString("bitbucket-server-token")
+
+	extra := comment.BitbucketServerExtra{
+		ServerURL: serverURL,
+		Token:     token,
+	}
+
+	return cmdHandler(ctx, cmd, "bitbucket-server", project, targetType, targetRef, extra)
+}
+
+// bitbucketServerCmd represents the bitbucket-server command
+var bitbucketServerCmd = &cobra.Command{
+	Use:   "bitbucket-server",
+	Short: "Post a comment to a Bitbucket Server pull request or commit",
+	Example: `
+  • Update a comment on a pull request:
+      $ compost bitbucket-server update INFRA/compost-example pull-request 3 --bitbucket-server-url=https://bitbucket.example.com --body="my comment"
+
+  • Update a comment on a commit:
+      $ compost bitbucket-server update INFRA/compost-example commit 2ca7182 --bitbucket-server-url=https://bitbucket.example.com --body="my comment"`,
+}
+
+// bitbucketServerUpdateCmd represents the bitbucket-server update command
+var bitbucketServerUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a comment on a Bitbucket Server pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketServerCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateComment(ctx, body)
+	}),
+}
+
+// bitbucketServerUpdateWithHistoryCmd represents the bitbucket-server update-with-history command
+var bitbucketServerUpdateWithHistoryCmd = &cobra.Command{
+	Use:   "update-with-history",
+	Short: "Update a comment on a Bitbucket Server pull request or commit, keeping the previous versions collapsed below it",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketServerCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateCommentWithHistory(ctx, body)
+	}),
+}
+
+// bitbucketServerUpdateSectionCmd represents the bitbucket-server update-section command
+var bitbucketServerUpdateSectionCmd = &cobra.Command{
+	Use:   "update-section",
+	Short: "Update a section of a comment on a Bitbucket Server pull request or commit, keeping the other sections unchanged",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  updateSectionRunE(bitbucketServerCmdHandler),
+}
+
+// bitbucketServerNewCmd represents the bitbucket-server new command
+var bitbucketServerNewCmd = &cobra.Command{
+	Use:   "new",
+	Short: "Create a new comment on a Bitbucket Server pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketServerCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.NewComment(ctx, body)
+	}),
+}
+
+// bitbucketServerDeleteAndNewCmd represents the bitbucket-server delete-and-new command
+var bitbucketServerDeleteAndNewCmd = &cobra.Command{
+	Use:   "delete-and-new",
+	Short: "Delete existing comments and create a new comment on a Bitbucket Server pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(bitbucketServerCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.DeleteAndNewComment(ctx, body)
+	}),
+}
+
+// bitbucketServerLatestCmd represents the bitbucket-server latest command
+var bitbucketServerLatestCmd = &cobra.Command{
+	Use:   "latest",
+	Short: "Return the latest comment on a Bitbucket Server pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: getCommentRunE(bitbucketServerCmdHandler, func(ctx context.Context, handler *comment.CommentHandler) (comment.Comment, error) {
+		return handler.LatestMatchingComment(ctx)
+	}),
+}
+
+// bitbucketServerListCmd represents the bitbucket-server list command
+var bitbucketServerListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the matching comments on a Bitbucket Server pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  listCommentsRunE(bitbucketServerCmdHandler),
+}
+
+func init() {
+	rootCmd.AddCommand(bitbucketServerCmd)
+
+	bitbucketServerCmd.PersistentFlags().String("tag", "", "Customize the embedded tag that is used for detecting comments posted by Compost")
+	bitbucketServerCmd.PersistentFlags().StringToString("meta", map[string]string{}, "Add a key=value pair to the metadata embedded in the comment, can be repeated")
+	bitbucketServerCmd.PersistentFlags().Bool("dry-run", false, "Find the comments but only report what would be posted, updated, deleted or hidden")
+	bitbucketServerCmd.PersistentFlags().String("bitbucket-server-url", "", "Bitbucket Server URL, e.g. https://bitbucket.example.com")
+	bitbucketServerCmd.PersistentFlags().String("bitbucket-server-token", "", "Bitbucket Server token, either an access token or username:password")
+
+	bitbucketServerCmd.AddCommand(bitbucketServerUpdateCmd)
+	bitbucketServerCmd.AddCommand(bitbucketServerUpdateWithHistoryCmd)
+	bitbucketServerCmd.AddCommand(bitbucketServerUpdateSectionCmd)
+	bitbucketServerCmd.AddCommand(bitbucketServerNewCmd)
+	bitbucketServerCmd.AddCommand(bitbucketServerDeleteAndNewCmd)
+	bitbucketServerCmd.AddCommand(bitbucketServerLatestCmd)
+	bitbucketServerCmd.AddCommand(bitbucketServerListCmd)
+
+	// Add the body flags to any commands that post comments
+	for _, cmd := range []*cobra.Command{bitbucketServerUpdateCmd, bitbucketServerUpdateWithHistoryCmd, bitbucketServerUpdateSectionCmd, bitbucketServerNewCmd, bitbucketServerDeleteAndNewCmd} {
+		addBodyFlags(cmd)
+	}
+
+	addHistoryFlags(bitbucketServerUpdateWithHistoryCmd)
+
+	bitbucketServerUpdateSectionCmd.Flags().String("section", "", "ID of the section of the comment to update")
+}
@@ -44,17 +44,35 @@ var githubUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update a comment on a GitHub pull request or commit",
 	Args:  cobra.ExactValidArgs(3),
-	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.UpdateComment(ctx, body)
 	}),
 }
 
+// githubUpdateWithHistoryCmd represents the github update-with-history command
+var githubUpdateWithHistoryCmd = &cobra.Command{
+	Use:   "update-with-history",
+	Short: "Update a comment on a GitHub pull request or commit, keeping the previous versions collapsed below it",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateCommentWithHistory(ctx, body)
+	}),
+}
+
+// githubUpdateSectionCmd represents the github update-section command
+var githubUpdateSectionCmd = &cobra.Command{
+	Use:   "update-section",
+	Short: "Update a section of a comment on a GitHub pull request or commit, keeping the other sections unchanged",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  updateSectionRunE(githubCmdHandler),
+}
+
 // githubNewCmd represents the github new command
 var githubNewCmd = &cobra.Command{
 	Use:   "new",
 	Short: "Create a new comment on a GitHub pull request or commit",
 	Args:  cobra.ExactValidArgs(3),
-	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.NewComment(ctx, body)
 	}),
 }
@@ -64,7 +82,7 @@ var githubHideAndNewCmd = &cobra.Command{
 	Use:   "hide-and-new",
 	Short: "Hide existing comments and create a new comment on a GitHub pull request or commit",
 	Args:  cobra.ExactValidArgs(3),
-	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.HideAndNewComment(ctx, body)
 	}),
 }
@@ -74,11 +92,31 @@ var githubDeleteAndNewCmd = &cobra.Command{
 	Use:   "delete-and-new",
 	Short: "Delete existing comments and create a new comment on a GitHub pull request or commit",
 	Args:  cobra.ExactValidArgs(3),
-	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.DeleteAndNewComment(ctx, body)
 	}),
 }
 
+// githubDeleteCmd represents the github delete command
+var githubDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete existing comments on a GitHub pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: cleanupCommentsRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, opts comment.CleanupOptions) (*comment.Result, error) {
+		return handler.DeleteComments(ctx, opts)
+	}),
+}
+
+// githubHideCmd represents the github hide command
+var githubHideCmd = &cobra.Command{
+	Use:   "hide",
+	Short: "Hide existing comments on a GitHub pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: cleanupCommentsRunE(githubCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, opts comment.CleanupOptions) (*comment.Result, error) {
+		return handler.HideComments(ctx, opts)
+	}),
+}
+
 // githubLatestCmd represents the github latest command
 var githubLatestCmd = &cobra.Command{
 	Use:   "latest",
@@ -89,22 +127,46 @@ var githubLatestCmd = &cobra.Command{
 	}),
 }
 
+// githubListCmd represents the github list command
+var githubListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the matching comments on a GitHub pull request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  listCommentsRunE(githubCmdHandler),
+}
+
 func init() {
 	rootCmd.AddCommand(githubCmd)
 
 	githubCmd.PersistentFlags().String("tag", "", "Customize the embedded tag that is used for detecting comments posted by Compost")
+	githubCmd.PersistentFlags().StringToString("meta", map[string]string{}, "Add a key=value pair to the metadata embedded in the comment, can be repeated")
+	githubCmd.PersistentFlags().Bool("dry-run", false, "Find the comments but only report what would be posted, updated, deleted or hidden")
+	githubCmd.PersistentFlags().String("author", "", "Only match comments written by this user, use 'self' for the user the token belongs to (GitHub and GitLab only)")
 	githubCmd.PersistentFlags().String("github-api-url", "", "GitHub API URL, defaults to https://api.github.com")
 	githubCmd.PersistentFlags().String("github-token", "", "GitHub token")
 
 	githubCmd.AddCommand(githubUpdateCmd)
+	githubCmd.AddCommand(githubUpdateWithHistoryCmd)
+	githubCmd.AddCommand(githubUpdateSectionCmd)
 	githubCmd.AddCommand(githubNewCmd)
 	githubCmd.AddCommand(githubHideAndNewCmd)
 	githubCmd.AddCommand(githubDeleteAndNewCmd)
+	githubCmd.AddCommand(githubDeleteCmd)
+	githubCmd.AddCommand(githubHideCmd)
 	githubCmd.AddCommand(githubLatestCmd)
+	githubCmd.AddCommand(githubListCmd)
+
+	// Add the body flags to any commands that post comments
+	for _, cmd := range []*cobra.Command{githubUpdateCmd, githubUpdateWithHistoryCmd, githubUpdateSectionCmd, githubNewCmd, githubHideAndNewCmd, githubDeleteAndNewCmd} {
+		addBodyFlags(cmd)
+	}
+
+	addHistoryFlags(githubUpdateWithHistoryCmd)
+
+	githubUpdateSectionCmd.Flags().String("section", "", "ID of the section of the comment to update")
 
-	// Add the body and body-file flags to any commands that post comments
-	for _, cmd := range []*cobra.Command{githubUpdateCmd, githubNewCmd, githubHideAndNewCmd, githubDeleteAndNewCmd} {
-		cmd.Flags().String("body", "", "Body of comment to post, mutually exclusive with body-file")
-		cmd.Flags().String("body-file", "", "File containing body of comment to post, mutually exclusive with body")
+	// Add the cleanup flags to any commands that delete or hide comments without posting
+	for _, cmd := range []*cobra.Command{githubDeleteCmd, githubHideCmd} {
+		addCleanupFlags(cmd)
 	}
 }
@@ -44,31 +44,79 @@ var gitlabUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update a comment on a GitLab merge request or commit",
 	Args:  cobra.ExactValidArgs(3),
-	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.UpdateComment(ctx, body)
 	}),
 }
 
+// gitlabUpdateWithHistoryCmd represents the gitlab update-with-history command
+var gitlabUpdateWithHistoryCmd = &cobra.Command{
+	Use:   "update-with-history",
+	Short: "Update a comment on a GitLab merge request or commit, keeping the previous versions collapsed below it",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.UpdateCommentWithHistory(ctx, body)
+	}),
+}
+
+// gitlabUpdateSectionCmd represents the gitlab update-section command
+var gitlabUpdateSectionCmd = &cobra.Command{
+	Use:   "update-section",
+	Short: "Update a section of a comment on a GitLab merge request or commit, keeping the other sections unchanged",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  updateSectionRunE(gitlabCmdHandler),
+}
+
 // gitlabNewCmd represents the gitlab new command
 var gitlabNewCmd = &cobra.Command{
 	Use:   "new",
 	Short: "Create a new comment on a GitLab merge request or commit",
 	Args:  cobra.ExactValidArgs(3),
-	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.NewComment(ctx, body)
 	}),
 }
 
+// gitlabHideAndNewCmd represents the gitlab hide-and-new command
+var gitlabHideAndNewCmd = &cobra.Command{
+	Use:   "hide-and-new",
+	Short: "Hide existing comments and create a new comment on a GitLab merge request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+		return handler.HideAndNewComment(ctx, body)
+	}),
+}
+
 // gitlabDeleteAndNewCmd represents the gitlab delete-and-new command
 var gitlabDeleteAndNewCmd = &cobra.Command{
 	Use:   "delete-and-new",
 	Short: "Delete existing comments and create a new comment on a GitLab merge request or commit",
 	Args:  cobra.ExactValidArgs(3),
-	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) error {
+	RunE: postCommentRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
 		return handler.DeleteAndNewComment(ctx, body)
 	}),
 }
 
+// gitlabDeleteCmd represents the gitlab delete command
+var gitlabDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete existing comments on a GitLab merge request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: cleanupCommentsRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, opts comment.CleanupOptions) (*comment.Result, error) {
+		return handler.DeleteComments(ctx, opts)
+	}),
+}
+
+// gitlabHideCmd represents the gitlab hide command
+var gitlabHideCmd = &cobra.Command{
+	Use:   "hide",
+	Short: "Hide existing comments on a GitLab merge request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE: cleanupCommentsRunE(gitlabCmdHandler, func(ctx context.Context, handler *comment.CommentHandler, opts comment.CleanupOptions) (*comment.Result, error) {
+		return handler.HideComments(ctx, opts)
+	}),
+}
+
 // gitlabLatestCmd represents the gitlab latest command
 var gitlabLatestCmd = &cobra.Command{
 	Use:   "latest",
@@ -79,21 +127,46 @@ var gitlabLatestCmd = &cobra.Command{
 	}),
 }
 
+// gitlabListCmd represents the gitlab list command
+var gitlabListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the matching comments on a GitLab merge request or commit",
+	Args:  cobra.ExactValidArgs(3),
+	RunE:  listCommentsRunE(gitlabCmdHandler),
+}
+
 func init() {
 	rootCmd.AddCommand(gitlabCmd)
 
 	gitlabCmd.PersistentFlags().String("tag", "", "Customize the embedded tag that is used for detecting comments posted by Compost")
+	gitlabCmd.PersistentFlags().StringToString("meta", map[string]string{}, "Add a key=value pair to the metadata embedded in the comment, can be repeated")
+	gitlabCmd.PersistentFlags().Bool("dry-run", false, "Find the comments but only report what would be posted, updated, deleted or hidden")
+	gitlabCmd.PersistentFlags().String("author", "", "Only match comments written by this user, use 'self' for the user the token belongs to (GitHub and GitLab only)")
 	gitlabCmd.PersistentFlags().String("gitlab-server-url", "", "GitLab server URL, defaults to https://gitlab.com")
 	gitlabCmd.PersistentFlags().String("gitlab-token", "", "GitLab token")
 
 	gitlabCmd.AddCommand(gitlabUpdateCmd)
+	gitlabCmd.AddCommand(gitlabUpdateWithHistoryCmd)
+	gitlabCmd.AddCommand(gitlabUpdateSectionCmd)
 	gitlabCmd.AddCommand(gitlabNewCmd)
+	gitlabCmd.AddCommand(gitlabHideAndNewCmd)
 	gitlabCmd.AddCommand(gitlabDeleteAndNewCmd)
+	gitlabCmd.AddCommand(gitlabDeleteCmd)
+	gitlabCmd.AddCommand(gitlabHideCmd)
 	gitlabCmd.AddCommand(gitlabLatestCmd)
+	gitlabCmd.AddCommand(gitlabListCmd)
+
+	// Add the body flags to any commands that post comments
+	for _, cmd := range []*cobra.Command{gitlabUpdateCmd, gitlabUpdateWithHistoryCmd, gitlabUpdateSectionCmd, gitlabNewCmd, gitlabHideAndNewCmd, gitlabDeleteAndNewCmd} {
+		addBodyFlags(cmd)
+	}
+
+	addHistoryFlags(gitlabUpdateWithHistoryCmd)
+
+	gitlabUpdateSectionCmd.Flags().String("section", "", "ID of the section of the comment to update")
 
-	// Add the body and body-file flags to any commands that post comments
-	for _, cmd := range []*cobra.Command{gitlabUpdateCmd, gitlabNewCmd, gitlabDeleteAndNewCmd} {
-		cmd.Flags().String("body", "", "Body of comment to post, mutually exclusive with body-file")
-		cmd.Flags().String("body-file", "", "File containing body of comment to post, mutually exclusive with body")
+	// Add the cleanup flags to any commands that delete or hide comments without posting
+	for _, cmd := range []*cobra.Command{gitlabDeleteCmd, gitlabHideCmd} {
+		addCleanupFlags(cmd)
 	}
 }
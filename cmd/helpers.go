@@ -2,16 +2,21 @@ package cmd
 
 import (
 	"compost/internal/comment"
+	"compost/internal/version"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 type commentHandlerFactory func(ctx context.Context, cmd *cobra.Command, args []string) (*comment.CommentHandler, error)
-type postCommentFunc func(ctx context.Context, handler *comment.CommentHandler, body string) error
+type postCommentFunc func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error)
+type cleanupCommentsFunc func(ctx context.Context, handler *comment.CommentHandler, opts comment.CleanupOptions) (*comment.Result, error)
 type getCommentFunc func(ctx context.Context, handler *comment.CommentHandler) (comment.Comment, error)
 
 // processArgs process the common args for all commands that post and get comments for a platform.
@@ -58,51 +63,245 @@ func processPlatform(s string, allowEmpty bool) (string, error) {
 	}
 
 	v, ok := map[string]string{
-		"github": "github",
-		"gitlab": "gitlab",
-		"":       "",
+		"github":           "github",
+		"gitlab":           "gitlab",
+		"bitbucket":        "bitbucket",
+		"bitbucket-server": "bitbucket-server",
+		"azure-repos":      "azure-repos",
+		"":                 "",
 	}[s]
 
 	if !ok {
-		return "", fmt.Errorf("Invalid platform '%s', valid options are 'github', 'gitlab'", s)
+		return "", fmt.Errorf("Invalid platform '%s', valid options are 'github', 'gitlab', 'bitbucket', 'bitbucket-server', 'azure-repos'", s)
 	}
 
 	return v, nil
 }
 
+// defaultTruncateFooter is the footer appended to the body when it is truncated.
+const defaultTruncateFooter = "*This comment has been truncated since it exceeds the maximum length.*"
+
+// addBodyFlags adds the flags for the body of the comment to a command that posts comments.
+func addBodyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("body", "", "Body of comment to post, mutually exclusive with body-file")
+	cmd.Flags().StringArray("body-file", nil, "File containing body of comment to post, use - for stdin. Can be repeated to concatenate files, mutually exclusive with body")
+	cmd.Flags().String("body-separator", `\n\n`, "Separator used to join the body files, header and footer. The escape sequences \\n, \\t and \\\\ are supported")
+	cmd.Flags().String("body-header", "", "File containing a header to add before the body, use - for stdin")
+	cmd.Flags().String("body-footer", "", "File containing a footer to add after the body, use - for stdin")
+	cmd.Flags().String("template", "", "File containing a Go text/template to render as the comment, with the body available as .Body")
+	cmd.Flags().StringToString("var", nil, "Variable to use when rendering the body template, in the form key=value. Can be repeated")
+	cmd.Flags().String("vars-file", "", "JSON file containing variables to use when rendering the body template")
+	cmd.Flags().String("on-overflow", "split", "What to do if the body is too long for a single comment, valid options are 'split', 'truncate'")
+	cmd.Flags().Int("max-length", 0, "Maximum length of the body when truncating, defaults to the maximum the platform allows")
+	cmd.Flags().String("truncate-footer", defaultTruncateFooter, "Footer appended to the body when it is truncated, e.g. a link to the full output")
+	cmd.Flags().Int("max-comment-length", 0, "Maximum number of characters the platform allows in a comment, defaults to the platform's limit")
+	cmd.Flags().String("result-file", "", "File to write the result of the command to as JSON")
+}
+
+// addHistoryFlags adds the flags for the previous versions to a command that updates
+// comments with history.
+func addHistoryFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("history-limit", 5, "Maximum number of previous versions to keep in the comment")
+}
+
+// addCleanupFlags adds the flags for selecting the comments to a command that deletes or hides comments.
+func addCleanupFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("keep-latest", 0, "Number of the most recent comments to keep")
+	cmd.Flags().Duration("older-than", 0, "Only select comments created longer ago than this, e.g. 72h")
+	cmd.Flags().String("result-file", "", "File to write the result of the command to as JSON")
+}
+
+// processCleanupFlags processes the keep-latest and older-than flags and returns the
+// options for selecting the comments to delete or hide.
+func processCleanupFlags(cmd *cobra.Command) (comment.CleanupOptions, error) {
+	keepLatest, _ := cmd.Flags().GetInt("keep-latest")
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+
+	if keepLatest < 0 {
+		return comment.CleanupOptions{}, fmt.Errorf("--keep-latest must not be negative")
+	}
+
+	if olderThan < 0 {
+		return comment.CleanupOptions{}, fmt.Errorf("--older-than must not be negative")
+	}
+
+	return comment.CleanupOptions{
+		KeepLatest: keepLatest,
+		OlderThan:  olderThan,
+	}, nil
+}
+
 // processBodyFlags processes the body and body-file flags and returns the body.
-// It returns an error if neither or both are set.
-// If body-file is set it reads the contents of the body file.
-func processBodyFlags(cmd *cobra.Command) (string, error) {
+// It returns an error if both are set, or neither are set and there is no template.
+// If body-file is set it reads the contents of the body files.
+// The header and footer are then added and the template is rendered if it's set.
+// The overflow flags are processed first so invalid values are rejected before the
+// template is rendered, and so the comment handler truncates the body if required.
+func processBodyFlags(cmd *cobra.Command, handler *comment.CommentHandler) (string, error) {
+	err := processOverflowFlags(cmd, handler)
+	if err != nil {
+		return "", err
+	}
+
+	templateFile, _ := cmd.Flags().GetString("template")
+
+	body, err := readBody(cmd, templateFile != "")
+	if err != nil {
+		return "", err
+	}
+
+	return processTemplateFlags(cmd, handler, body)
+}
+
+// separatorEscapes replaces the escape sequences in the body separator, since they
+// can't be easily passed on the command line.
+var separatorEscapes = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t")
+
+// readBody reads the body from the body or body-file flags and adds the header and
+// footer. If there are multiple body files, or a header or footer, they are joined
+// with the body separator. If optional is true neither flag has to be set.
+func readBody(cmd *cobra.Command, optional bool) (string, error) {
 	bodySet := cmd.Flags().Changed("body")
 	bodyFileSet := cmd.Flags().Changed("body-file")
 
-	if !bodySet && !bodyFileSet {
-		return "", fmt.Errorf("--body or --body-file must be set")
+	if !bodySet && !bodyFileSet && !optional {
+		return "", fmt.Errorf("--body, --body-file or --template must be set")
 	}
 
 	if bodySet && bodyFileSet {
 		return "", fmt.Errorf("--body and --body-file cannot be set at the same time")
 	}
 
+	bodyFiles, _ := cmd.Flags().GetStringArray("body-file")
+	header, _ := cmd.Flags().GetString("body-header")
+	footer, _ := cmd.Flags().GetString("body-footer")
+	separator, _ := cmd.Flags().GetString("body-separator")
+	separator = separatorEscapes.Replace(separator)
+
+	files := bodyFiles
+	if header != "" {
+		files = append([]string{header}, files...)
+	}
+	if footer != "" {
+		files = append(files, footer)
+	}
+
+	stdinCount := 0
+	for _, file := range files {
+		if file == "-" {
+			stdinCount++
+		}
+	}
+
+	if stdinCount > 1 {
+		return "", fmt.Errorf("Only one of --body-file, --body-header or --body-footer can read from stdin")
+	}
+
+	var parts []string
+
+	if header != "" {
+		b, err := readBodyFile(cmd, header)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to read body header")
+		}
+		parts = append(parts, b)
+	}
+
 	if bodySet {
 		body, _ := cmd.Flags().GetString("body")
-		return body, nil
+		parts = append(parts, body)
 	}
 
-	bodyFile, _ := cmd.Flags().GetString("body-file")
-	b, err := os.ReadFile(bodyFile)
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to read body file")
+	for _, bodyFile := range bodyFiles {
+		b, err := readBodyFile(cmd, bodyFile)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to read body file")
+		}
+		parts = append(parts, b)
+	}
+
+	if footer != "" {
+		b, err := readBodyFile(cmd, footer)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to read body footer")
+		}
+		parts = append(parts, b)
+	}
+
+	if len(parts) == 1 {
+		return parts[0], nil
 	}
 
-	return string(b), nil
+	// Trailing newlines are removed so the parts are only separated by the separator
+	for i, part := range parts {
+		parts[i] = strings.TrimRight(part, "\r\n")
+	}
+
+	return strings.Join(parts, separator), nil
+}
+
+// readBodyFile reads the contents of a body file, or stdin if the path is -.
+func readBodyFile(cmd *cobra.Command, path string) (string, error) {
+	if path == "-" {
+		b, err := io.ReadAll(cmd.InOrStdin())
+		return string(b), err
+	}
+
+	b, err := os.ReadFile(path)
+	return string(b), err
+}
+
+// processOverflowFlags processes the on-overflow, max-length and truncate-footer flags.
+// If the body should be truncated the comment handler is set to truncate the final body
+// of the comment, e.g. after a section is merged or the history is added, before it adds
+// the tag so the tag is never cut off. Otherwise the comment handler splits the body if
+// it is too long.
+func processOverflowFlags(cmd *cobra.Command, handler *comment.CommentHandler) error {
+	onOverflow, _ := cmd.Flags().GetString("on-overflow")
+	maxLength, _ := cmd.Flags().GetInt("max-length")
+	footer, _ := cmd.Flags().GetString("truncate-footer")
+
+	if onOverflow != "split" && onOverflow != "truncate" {
+		return fmt.Errorf("Invalid on-overflow '%s', valid options are 'split', 'truncate'", onOverflow)
+	}
+
+	if onOverflow != "truncate" {
+		if cmd.Flags().Changed("max-length") {
+			return fmt.Errorf("--max-length can only be used with --on-overflow=truncate")
+		}
+
+		return nil
+	}
+
+	if maxLength < 0 {
+		return fmt.Errorf("--max-length must not be negative")
+	}
+
+	handler.Truncate = &comment.TruncateOptions{
+		MaxLength: maxLength,
+		Footer:    footer,
+	}
+
+	return nil
 }
 
 // cmdHandler processes common args for all commands
 // and returns the comment handler for posting/retrieving comments on the given platform.
 func cmdHandler(ctx context.Context, cmd *cobra.Command, platform string, project string, targetType string, targetRef string, extra interface{}) (*comment.CommentHandler, error) {
 	tag, _ := cmd.Flags().GetString("tag")
+	meta, _ := cmd.Flags().GetStringToString("meta")
+	author, _ := cmd.Flags().GetString("author")
+	historyLimit, _ := cmd.Flags().GetInt("history-limit")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	maxCommentLength, _ := cmd.Flags().GetInt("max-comment-length")
+
+	if maxCommentLength < 0 {
+		return nil, fmt.Errorf("--max-comment-length must not be negative")
+	}
+
+	if author != "" && platform != "github" && platform != "gitlab" {
+		return nil, fmt.Errorf("--author is only supported for GitHub and GitLab")
+	}
 
 	platformHandlerFactory, err := comment.NewPlatformHandlerFactory(ctx, platform, targetType)
 	if err != nil {
@@ -114,30 +313,114 @@ func cmdHandler(ctx context.Context, cmd *cobra.Command, platform string, projec
 		return nil, err
 	}
 
-	return &comment.CommentHandler{
-		PlatformHandler: platformHandler,
-		Tag:             tag,
-	}, nil
+	// The report is written to stderr so it doesn't mix with the output
+	if dryRun {
+		log.Ctx(ctx).Info().Msg("Dry run enabled, no comments will be posted, updated, deleted or hidden")
+		platformHandler = comment.NewDryRunPlatformHandler(platformHandler, cmd.ErrOrStderr())
+	}
+
+	handler, err := comment.NewCommentHandler(ctx, platformHandler, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	handler.Author = author
+	handler.HistoryLimit = historyLimit
+	handler.MaxCommentLength = maxCommentLength
+	handler.Target = comment.Target{
+		Platform:   platform,
+		Project:    project,
+		TargetType: targetType,
+		TargetRef:  targetRef,
+	}
+	handler.Metadata = comment.Metadata{
+		CompostVersion: version.Version,
+		Values:         meta,
+	}
+
+	if targetType == "commit" {
+		handler.Metadata.CommitSHA = targetRef
+	}
+
+	return handler, nil
 }
 
 // postCommentRunE contains the common logic for any command that posts comments.
 // It sets up the logger, creates the comment handler, processes the args and flags
-// and calls the handlerFunc to post the comment.
+// and calls the handlerFunc to post the comment. The result is output to stdout
+// unless the output format is text, and written to the result file if set.
 func postCommentRunE(handlerFactory commentHandlerFactory, handlerFunc postCommentFunc) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
+		output, err := processOutputFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		handler, err := handlerFactory(ctx, cmd, args)
+		if err != nil {
+			return err
+		}
+
+		body, err := processBodyFlags(cmd, handler)
+		if err != nil {
+			return err
+		}
+
+		result, err := handlerFunc(ctx, handler, body)
+		if err != nil {
+			return err
+		}
+
+		return writeResult(cmd, output, result)
+	}
+}
+
+// updateSectionRunE contains the logic for the update-section commands.
+// It reads the section flag and updates that section of the comment with the body.
+func updateSectionRunE(handlerFactory commentHandlerFactory) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		section, _ := cmd.Flags().GetString("section")
+		if section == "" {
+			return fmt.Errorf("--section must be set")
+		}
+
+		return postCommentRunE(handlerFactory, func(ctx context.Context, handler *comment.CommentHandler, body string) (*comment.Result, error) {
+			return handler.UpdateCommentSection(ctx, section, body)
+		})(cmd, args)
+	}
+}
+
+// cleanupCommentsRunE contains the common logic for any command that deletes or hides
+// comments without posting a new one. It creates the comment handler, processes the args
+// and flags and calls the handlerFunc to clean up the comments. The result is output to
+// stdout unless the output format is text, and written to the result file if set.
+func cleanupCommentsRunE(handlerFactory commentHandlerFactory, handlerFunc cleanupCommentsFunc) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		output, err := processOutputFlag(cmd)
+		if err != nil {
+			return err
+		}
+
 		handler, err := handlerFactory(ctx, cmd, args)
 		if err != nil {
 			return err
 		}
 
-		body, err := processBodyFlags(cmd)
+		opts, err := processCleanupFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		result, err := handlerFunc(ctx, handler, opts)
 		if err != nil {
 			return err
 		}
 
-		return handlerFunc(ctx, handler, body)
+		return writeResult(cmd, output, result)
 	}
 }
 
@@ -148,6 +431,11 @@ func getCommentRunE(handlerFactory commentHandlerFactory, handlerFunc getComment
 	return func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
+		output, err := processOutputFlag(cmd)
+		if err != nil {
+			return err
+		}
+
 		handler, err := handlerFactory(ctx, cmd, args)
 		if err != nil {
 			return err
@@ -158,10 +446,55 @@ func getCommentRunE(handlerFactory commentHandlerFactory, handlerFunc getComment
 			return err
 		}
 
-		if comment.Body() != "" {
+		if output != "text" {
+			var v *commentOutput
+			if comment != nil {
+				o := newCommentOutput(comment)
+				v = &o
+			}
+
+			return writeOutput(cmd.OutOrStdout(), output, v)
+		}
+
+		if comment != nil && comment.Body() != "" {
 			cmd.Println(comment.Body())
 		}
 
 		return nil
 	}
 }
+
+// listCommentsRunE contains the common logic for any command that lists comments.
+// It creates the comment handler, processes the args and flags and outputs all
+// the matching comments to stdout in the output format.
+func listCommentsRunE(handlerFactory commentHandlerFactory) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		output, err := processOutputFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		handler, err := handlerFactory(ctx, cmd, args)
+		if err != nil {
+			return err
+		}
+
+		comments, err := handler.MatchingComments(ctx)
+		if err != nil {
+			return err
+		}
+
+		if output == "text" {
+			return writeCommentsTable(cmd.OutOrStdout(), comments)
+		}
+
+		outputs := make([]commentOutput, 0, len(comments))
+		for _, c := range comments {
+			outputs = append(outputs, newCommentOutput(c))
+		}
+
+		return writeOutput(cmd.OutOrStdout(), output, outputs)
+	}
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"compost/internal/comment"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// commentOutput is the structure of a comment in the JSON and YAML output.
+type commentOutput struct {
+	ID        string            `json:"id" yaml:"id"`
+	Ref       string            `json:"ref" yaml:"ref"`
+	CreatedAt string            `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+	UpdatedAt string            `json:"updatedAt,omitempty" yaml:"updatedAt,omitempty"`
+	IsHidden  bool              `json:"isHidden" yaml:"isHidden"`
+	Author    string            `json:"author,omitempty" yaml:"author,omitempty"`
+	Metadata  *comment.Metadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Body      yamlText          `json:"body" yaml:"body"`
+}
+
+// newCommentOutput converts the comment into the structure used for the JSON and YAML output.
+func newCommentOutput(c comment.Comment) commentOutput {
+	return commentOutput{
+		ID:        c.ID(),
+		Ref:       c.Ref(),
+		CreatedAt: formatTime(c.CreatedAt()),
+		UpdatedAt: formatTime(c.UpdatedAt()),
+		IsHidden:  c.IsHidden(),
+		Author:    c.Author(),
+		Metadata:  c.Metadata(),
+		Body:      yamlText(c.Body()),
+	}
+}
+
+// formatTime formats the time for the output. It returns an empty string if the time is not known.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// processOutputFlag processes the output flag and returns the output format.
+// It returns an error if the output format is not supported.
+func processOutputFlag(cmd *cobra.Command) (string, error) {
+	output, _ := cmd.Flags().GetString("output")
+
+	if output != "text" && output != "json" && output != "yaml" {
+		return "", fmt.Errorf("Invalid output format '%s', valid options are 'text', 'json', 'yaml'", output)
+	}
+
+	return output, nil
+}
+
+// writeOutput writes the value to the writer in the given format, which must be json or yaml.
+func writeOutput(w io.Writer, format string, v interface{}) error {
+	var b []byte
+	var err error
+
+	if format == "yaml" {
+		b, err = marshalYAML(v)
+	} else {
+		b, err = json.MarshalIndent(v, "", "  ")
+		b = append(b, '\n')
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// writeResult writes the result of a command that posts or cleans up comments. It's written
+// to the result file as JSON if the result-file flag is set, and to stdout in the output
+// format unless the output format is text.
+func writeResult(cmd *cobra.Command, output string, result *comment.Result) error {
+	resultFile, _ := cmd.Flags().GetString("result-file")
+
+	if resultFile != "" {
+		f, err := os.Create(resultFile)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create result file")
+		}
+		defer f.Close()
+
+		err = writeOutput(f, "json", result)
+		if err != nil {
+			return errors.Wrap(err, "Failed to write result file")
+		}
+	}
+
+	if output == "text" {
+		return nil
+	}
+
+	return writeOutput(cmd.OutOrStdout(), output, result)
+}
+
+// writeCommentsTable writes a table summarizing the comments to the writer, for the text output.
+func writeCommentsTable(w io.Writer, comments []comment.Comment) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "ID\tCREATED\tAUTHOR\tHIDDEN\tREF")
+
+	for _, c := range comments {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\n", c.ID(), formatTime(c.CreatedAt()), c.Author(), c.IsHidden(), c.Ref())
+	}
+
+	return tw.Flush()
+}
+
+// yamlText is a string that may have multiple lines. yaml.v3 loses a leading newline
+// when it writes a string as a literal block, so those strings are double quoted instead.
+type yamlText string
+
+// MarshalYAML returns the YAML node for the string.
+func (s yamlText) MarshalYAML() (interface{}, error) {
+	if strings.HasPrefix(string(s), "\n") {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(s), Style: yaml.DoubleQuotedStyle}, nil
+	}
+
+	return string(s), nil
+}
+
+// marshalYAML encodes the value as YAML. The keys are in the same order as the
+// struct fields.
+func marshalYAML(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
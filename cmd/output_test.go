@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"compost/internal/comment"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			name: "nil",
+			v:    nil,
+			want: "null\n",
+		},
+		{
+			name: "result without comments",
+			v:    &comment.Result{Comments: []comment.CommentResult{}},
+			want: "comments: []\n",
+		},
+		{
+			name: "result",
+			v: &comment.Result{
+				Action: comment.ActionCreated,
+				ID:     "1",
+				Ref:    "ref-1",
+				Comments: []comment.CommentResult{
+					{Action: comment.ActionDeleted, ID: "2", Ref: "ref-2"},
+					{Action: comment.ActionCreated, ID: "1", Ref: "ref-1"},
+				},
+			},
+			want: "action: created\nid: \"1\"\nref: ref-1\ncomments:\n  - action: deleted\n    id: \"2\"\n    ref: ref-2\n  - action: created\n    id: \"1\"\n    ref: ref-1\n",
+		},
+		{
+			name: "comment with metadata",
+			v: commentOutput{
+				ID:  "1",
+				Ref: "ref-1",
+				Metadata: &comment.Metadata{
+					Version:   1,
+					Tag:       "plan",
+					CommitSHA: "abc",
+					Part:      1,
+					Parts:     2,
+				},
+				Body: "body",
+			},
+			want: "id: \"1\"\nref: ref-1\nisHidden: false\nmetadata:\n  version: 1\n  tag: plan\n  commitSha: abc\n  part: 1\n  parts: 2\nbody: body\n",
+		},
+		{
+			name: "comment with multi-line body",
+			v:    commentOutput{ID: "1", Ref: "ref-1", Body: "a\n\n```\nb: c\n```"},
+			want: "id: \"1\"\nref: ref-1\nisHidden: false\nbody: |-\n  a\n\n  ```\n  b: c\n  ```\n",
+		},
+		{
+			name: "comment with body starting with a newline",
+			v:    commentOutput{ID: "1", Ref: "ref-1", Body: "\n  a\nb\n"},
+			want: "id: \"1\"\nref: ref-1\nisHidden: false\nbody: \"\\n  a\\nb\\n\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalYAML(tt.v)
+			if err != nil {
+				t.Fatalf("marshalYAML() error = %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("marshalYAML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalYAMLRoundTrip(t *testing.T) {
+	bodies := []string{
+		"",
+		"body",
+		"\nstarts with a newline",
+		"  indented\nlines\n",
+		"trailing newlines\n\n",
+		"# not a comment: [x]\n\t- tab",
+	}
+
+	for _, body := range bodies {
+		b, err := marshalYAML(commentOutput{Body: yamlText(body)})
+		if err != nil {
+			t.Fatalf("marshalYAML() error = %v", err)
+		}
+
+		var out struct {
+			Body string `yaml:"body"`
+		}
+
+		err = yaml.Unmarshal(b, &out)
+		if err != nil {
+			t.Fatalf("yaml.Unmarshal() error = %v", err)
+		}
+
+		if out.Body != body {
+			t.Errorf("body %q was decoded as %q from %q", body, out.Body, b)
+		}
+	}
+}
@@ -78,4 +78,5 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().String("log-level", "", "Log level: trace, debug, info, warn, error, fatal")
+	rootCmd.PersistentFlags().String("output", "text", "Output format for the comments, or the result of posting comments: text, json, yaml")
 }
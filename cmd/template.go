@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"compost/internal/comment"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// templateData is the data that body templates are rendered with.
+type templateData struct {
+	// Body is the body from the body, body-file, body-header and body-footer flags. It's
+	// passed as data, rather than being rendered, since it can contain untrusted text.
+	Body string
+
+	Platform    string
+	Project     string
+	TargetType  string
+	TargetRef   string
+	CommitSHA   string
+	PipelineURL string
+
+	// Env contains the environment variables, e.g. the ones set by the CI system. Variables
+	// that may contain secrets are excluded.
+	Env map[string]string
+
+	// Vars contains the variables from the var and vars-file flags.
+	Vars map[string]interface{}
+
+	// Previous is the latest matching comment, or nil if there isn't one.
+	Previous *templateComment
+}
+
+// templateComment is a previously posted comment in the template data.
+type templateComment struct {
+	ID       string
+	Ref      string
+	Body     string
+	Metadata *comment.Metadata
+}
+
+// processTemplateFlags processes the template, var and vars-file flags. If template is
+// set the template file is rendered as a Go text/template, with the body passed to it
+// as data, otherwise the body is returned unchanged. It returns an error if var or
+// vars-file are set without template.
+func processTemplateFlags(cmd *cobra.Command, handler *comment.CommentHandler, body string) (string, error) {
+	templateFile, _ := cmd.Flags().GetString("template")
+	vars, _ := cmd.Flags().GetStringToString("var")
+	varsFile, _ := cmd.Flags().GetString("vars-file")
+
+	if templateFile == "" {
+		if len(vars) > 0 || varsFile != "" {
+			return "", fmt.Errorf("--var and --vars-file can only be set with --template")
+		}
+
+		return body, nil
+	}
+
+	tmpl, err := os.ReadFile(templateFile)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read template file")
+	}
+
+	data, err := newTemplateData(cmd, handler, vars, varsFile)
+	if err != nil {
+		return "", err
+	}
+
+	data.Body = body
+
+	return renderTemplate(string(tmpl), data)
+}
+
+// newTemplateData returns the data for rendering the body template, including the
+// latest matching comment.
+func newTemplateData(cmd *cobra.Command, handler *comment.CommentHandler, vars map[string]string, varsFile string) (templateData, error) {
+	data := templateData{
+		Platform:    handler.Target.Platform,
+		Project:     handler.Target.Project,
+		TargetType:  handler.Target.TargetType,
+		TargetRef:   handler.Target.TargetRef,
+		CommitSHA:   handler.Metadata.CommitSHA,
+		PipelineURL: handler.Metadata.PipelineURL,
+		Env:         environ(),
+		Vars:        map[string]interface{}{},
+	}
+
+	if varsFile != "" {
+		b, err := os.ReadFile(varsFile)
+		if err != nil {
+			return data, errors.Wrap(err, "Failed to read vars file")
+		}
+
+		err = json.Unmarshal(b, &data.Vars)
+		if err != nil {
+			return data, errors.Wrap(err, "Failed to parse vars file, it must contain a JSON object")
+		}
+	}
+
+	// The var flags override the values from the vars file
+	for k, v := range vars {
+		data.Vars[k] = v
+	}
+
+	previous, err := handler.LatestMatchingComment(cmd.Context())
+	if err != nil {
+		return data, errors.Wrap(err, "Failed to get the previous comment for the template")
+	}
+
+	if previous != nil {
+		data.Previous = &templateComment{
+			ID:       previous.ID(),
+			Ref:      previous.Ref(),
+			Body:     comment.BodyContent(previous),
+			Metadata: previous.Metadata(),
+		}
+	}
+
+	return data, nil
+}
+
+// renderTemplate renders the Go text/template with the given data. Referencing a
+// variable that isn't set is an error, so typos don't render as <no value>.
+func renderTemplate(text string, data templateData) (string, error) {
+	tmpl, err := template.New("body").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse body template")
+	}
+
+	var out strings.Builder
+
+	err = tmpl.Execute(&out, data)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to render body template")
+	}
+
+	return out.String(), nil
+}
+
+// secretEnvVarNames are the parts of the names of environment variables that may
+// contain secrets, e.g. GITHUB_TOKEN or SYSTEM_ACCESSTOKEN.
+var secretEnvVarNames = []string{"TOKEN", "SECRET", "PASSWORD", "CREDENTIAL"}
+
+// secretEnvVarWords are the words in the names of environment variables that may contain
+// secrets, e.g. INFRACOST_API_KEY or CI_JOB_JWT. They're only matched as whole words, or
+// at the end of a word for KEY, so names like PATH aren't excluded.
+var secretEnvVarWords = []string{"KEY", "PAT", "JWT", "AUTH"}
+
+// environ returns the environment variables as a map, excluding any that may contain
+// secrets so they can't be posted in a comment.
+func environ() map[string]string {
+	env := map[string]string{}
+
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+
+		if isSecretEnvVar(kv[:i]) {
+			continue
+		}
+
+		env[kv[:i]] = kv[i+1:]
+	}
+
+	return env
+}
+
+// isSecretEnvVar returns true if the name of the environment variable suggests it
+// contains a secret.
+func isSecretEnvVar(name string) bool {
+	name = strings.ToUpper(name)
+
+	for _, s := range secretEnvVarNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+
+	for _, word := range words {
+		for _, s := range secretEnvVarWords {
+			if word == s || (s == "KEY" && strings.HasSuffix(word, s)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import "testing"
+
+func TestIsSecretEnvVar(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "GITHUB_TOKEN", want: true},
+		{name: "SYSTEM_ACCESSTOKEN", want: true},
+		{name: "ci_job_token", want: true},
+		{name: "AWS_SECRET_ACCESS_KEY", want: true},
+		{name: "DB_PASSWORD", want: true},
+		{name: "GOOGLE_CREDENTIALS", want: true},
+		{name: "INFRACOST_API_KEY", want: true},
+		{name: "INFRACOST_APIKEY", want: true},
+		{name: "AZURE_DEVOPS_EXT_PAT", want: true},
+		{name: "CI_JOB_JWT", want: true},
+		{name: "CI_JOB_JWT_V2", want: true},
+		{name: "NPM_AUTH", want: true},
+		{name: "PATH", want: false},
+		{name: "CI_PROJECT_PATH", want: false},
+		{name: "CI_COMMIT_AUTHOR", want: false},
+		{name: "KEYBOARD_LAYOUT", want: false},
+		{name: "GITHUB_REPOSITORY", want: false},
+		{name: "BUILDKITE_PULL_REQUEST", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSecretEnvVar(tt.name); got != tt.want {
+				t.Errorf("isSecretEnvVar(%q) = %t, want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ module compost
 
 go 1.17
 
-require golang.org/x/oauth2 v0.0.0-20211104180415-d3ed0bb246c8
+require (
+	golang.org/x/oauth2 v0.0.0-20211104180415-d3ed0bb246c8
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/google/go-querystring v1.1.0 // indirect
@@ -0,0 +1,435 @@
+package comment
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// azureReposAPIVersion is the version of the Azure DevOps REST API used by the handlers.
+var azureReposAPIVersion = "6.0"
+
+// azureReposComment represents a comment on an Azure Repos pull request. It
+// implements the Comment interface.
+type azureReposComment struct {
+	id           int
+	threadID     int
+	body         string
+	createdAt    time.Time
+	updatedAt    time.Time
+	url          string
+	threadStatus string
+}
+
+// ID returns the ID of the thread and the ID of the comment in the thread,
+// separated by a slash.
+func (c *azureReposComment) ID() string {
+	return fmt.Sprintf("%d/%d", c.threadID, c.id)
+}
+
+// Body returns the body of the comment
+func (c *azureReposComment) Body() string {
+	return c.body
+}
+
+// Ref returns the reference to the comment. For Azure Repos this is a URL to the
+// HTML page of the comment thread.
+func (c *azureReposComment) Ref() string {
+	return c.url
+}
+
+// Less compares the comment to another comment and returns true if this
+// comment should be sorted before the other comment.
+func (c *azureReposComment) Less(other Comment) bool {
+	j := other.(*azureReposComment)
+
+	if !c.createdAt.Equal(j.createdAt) {
+		return c.createdAt.Before(j.createdAt)
+	}
+
+	if c.threadID != j.threadID {
+		return c.threadID < j.threadID
+	}
+
+	return c.id < j.id
+}
+
+// IsHidden returns true if the thread containing the comment has been closed.
+func (c *azureReposComment) IsHidden() bool {
+	return c.threadStatus == "closed"
+}
+
+// CreatedAt returns the time the comment was created.
+func (c *azureReposComment) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// UpdatedAt returns the time the comment was last updated.
+func (c *azureReposComment) UpdatedAt() time.Time {
+	return c.updatedAt
+}
+
+// Author always returns an empty string since filtering by author is not
+// supported for Azure Repos.
+func (c *azureReposComment) Author() string {
+	return ""
+}
+
+// Version always returns an empty string since comment versions are not
+// supported for Azure Repos.
+func (c *azureReposComment) Version() string {
+	return ""
+}
+
+// Metadata returns the metadata embedded in the comment, or nil if it has none.
+func (c *azureReposComment) Metadata() *Metadata {
+	return parseMetadata(c.body)
+}
+
+// AzureReposExtra contains any extra inputs that can be passed to the Azure Repos comment handlers.
+type AzureReposExtra struct {
+	// Token is the Azure DevOps API token. This can either be a personal access token
+	// or the System.AccessToken of a pipeline.
+	Token string
+}
+
+// azureReposRepo contains the parts of an Azure Repos repository URL.
+type azureReposRepo struct {
+	orgURL  string
+	project string
+	repo    string
+	repoURL string
+}
+
+// parseAzureReposURL parses an Azure Repos repository URL, e.g.
+// https://dev.azure.com/org/project/_git/repo or https://org.visualstudio.com/project/_git/repo.
+func parseAzureReposURL(repoURL string) (azureReposRepo, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return azureReposRepo{}, errors.Wrap(err, "Error parsing repository URL")
+	}
+
+	// Remove any username from the URL, e.g. https://org@dev.azure.com/org/project/_git/repo
+	u.User = nil
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/_git/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return azureReposRepo{}, fmt.Errorf("Invalid Azure Repos repository URL: %s, expecting https://dev.azure.com/org/project/_git/repo", repoURL)
+	}
+
+	orgAndProject := strings.Split(parts[0], "/")
+	project := orgAndProject[len(orgAndProject)-1]
+	repo := parts[1]
+
+	u.Path = "/" + strings.Join(orgAndProject[:len(orgAndProject)-1], "/")
+	orgURL := strings.TrimSuffix(u.String(), "/")
+
+	return azureReposRepo{
+		orgURL:  orgURL,
+		project: project,
+		repo:    repo,
+		repoURL: fmt.Sprintf("%s/%s/_git/%s", orgURL, url.PathEscape(project), url.PathEscape(repo)),
+	}, nil
+}
+
+// azureReposMaxBodyLength is the maximum number of characters Azure Repos allows in a comment body.
+const azureReposMaxBodyLength = 150000
+
+// azureReposPRHandler is a PlatformHandler for Azure Repos pull requests. It
+// implements the PlatformHandler interface and contains the functions
+// for finding, creating, updating, deleting and hiding comments on Azure Repos pull requests.
+type azureReposPRHandler struct {
+	httpClient *http.Client
+	token      string
+	repo       azureReposRepo
+	prNumber   int
+}
+
+// newAzureReposPRHandler creates a new PlatformHandler for Azure Repos pull requests.
+func newAzureReposPRHandler(ctx context.Context, project string, targetRef string, extra interface{}) (PlatformHandler, error) {
+	azureReposExtra, ok := extra.(AzureReposExtra)
+	if !ok {
+		return nil, errors.New("Invalid extra")
+	}
+
+	repo, err := parseAzureReposURL(project)
+	if err != nil {
+		return nil, err
+	}
+
+	prNumber, err := strconv.Atoi(targetRef)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing targetRef as pull request number")
+	}
+
+	h := &azureReposPRHandler{
+		httpClient: http.DefaultClient,
+		token:      azureReposExtra.Token,
+		repo:       repo,
+		prNumber:   prNumber,
+	}
+
+	return h, nil
+}
+
+// threadsURL returns the API URL for the pull request threads.
+func (h *azureReposPRHandler) threadsURL() string {
+	return fmt.Sprintf(
+		"%s/%s/_apis/git/repositories/%s/pullRequests/%d/threads",
+		h.repo.orgURL, url.PathEscape(h.repo.project), url.PathEscape(h.repo.repo), h.prNumber,
+	)
+}
+
+// threadRefURL returns the URL to the HTML page of the thread.
+func (h *azureReposPRHandler) threadRefURL(threadID int) string {
+	return fmt.Sprintf("%s/pullrequest/%d?discussionId=%d", h.repo.repoURL, h.prNumber, threadID)
+}
+
+// do sends a request to the Azure DevOps API and unmarshals the response into resData
+// if it is not nil. It returns the response headers, or an error if the response status
+// is not 200 OK.
+func (h *azureReposPRHandler) do(ctx context.Context, method string, reqURL string, reqData interface{}, resData interface{}) (http.Header, error) {
+	var reqBody io.Reader
+	if reqData != nil {
+		b, err := json.Marshal(reqData)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error marshaling request body")
+		}
+		reqBody = bytes.NewBuffer(b)
+	}
+
+	u, err := url.Parse(reqURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing request URL")
+	}
+	q := u.Query()
+	q.Set("api-version", azureReposAPIVersion)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", azureReposAuthHeader(h.token))
+
+	res, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Unexpected response: %s", res.Status)
+	}
+
+	if resData == nil {
+		return res.Header, nil
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading response body")
+	}
+
+	err = json.Unmarshal(resBody, resData)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling response body")
+	}
+
+	return res.Header, nil
+}
+
+// azureReposAuthHeader returns the Authorization header for the token.
+// Pipeline access tokens are JWTs and use bearer auth, personal access tokens
+// use basic auth with an empty username.
+func azureReposAuthHeader(token string) string {
+	if strings.Count(token, ".") == 2 {
+		return fmt.Sprintf("Bearer %s", token)
+	}
+
+	return fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(":"+token)))
+}
+
+// azureReposAPIComment is the structure of a thread comment returned by the Azure DevOps API.
+type azureReposAPIComment struct {
+	ID              int       `json:"id"`
+	Content         string    `json:"content"`
+	PublishedDate   time.Time `json:"publishedDate"`
+	LastUpdatedDate time.Time `json:"lastUpdatedDate"`
+	IsDeleted       bool      `json:"isDeleted"`
+	CommentType     string    `json:"commentType"`
+}
+
+// azureReposAPIThread is the structure of a thread returned by the Azure DevOps API.
+type azureReposAPIThread struct {
+	ID        int                    `json:"id"`
+	Status    string                 `json:"status"`
+	IsDeleted bool                   `json:"isDeleted"`
+	Comments  []azureReposAPIComment `json:"comments"`
+}
+
+// CallFindComments calls the Azure DevOps API to find all the pull request
+// comments. Comments from all threads on the pull request are returned.
+func (h *azureReposPRHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
+	var allComments []Comment
+
+	continuationToken := ""
+
+	// Get threads from all pages.
+	for {
+		reqURL := h.threadsURL()
+		if continuationToken != "" {
+			reqURL = fmt.Sprintf("%s?continuationToken=%s", reqURL, url.QueryEscape(continuationToken))
+		}
+
+		var resData struct {
+			Value []azureReposAPIThread `json:"value"`
+		}
+
+		header, err := h.do(ctx, "GET", reqURL, nil, &resData)
+		if err != nil {
+			return []Comment{}, errors.Wrap(err, "Error getting comments")
+		}
+
+		for _, thread := range resData.Value {
+			if thread.IsDeleted {
+				continue
+			}
+
+			for _, comment := range thread.Comments {
+				if comment.IsDeleted || comment.CommentType == "system" {
+					continue
+				}
+
+				allComments = append(allComments, &azureReposComment{
+					id:           comment.ID,
+					threadID:     thread.ID,
+					body:         comment.Content,
+					createdAt:    comment.PublishedDate,
+					updatedAt:    comment.LastUpdatedDate,
+					url:          h.threadRefURL(thread.ID),
+					threadStatus: thread.Status,
+				})
+			}
+		}
+
+		continuationToken = header.Get("x-ms-continuationtoken")
+		if continuationToken == "" {
+			break
+		}
+	}
+
+	return allComments, nil
+}
+
+// CallCreateComment calls the Azure DevOps API to create a new thread on the pull
+// request containing the comment.
+func (h *azureReposPRHandler) CallCreateComment(ctx context.Context, body string) (Comment, error) {
+	reqData := map[string]interface{}{
+		"comments": []map[string]interface{}{
+			{
+				"content":         body,
+				"parentCommentId": 0,
+				"commentType":     "text",
+			},
+		},
+		"status": "active",
+	}
+
+	var resData azureReposAPIThread
+
+	_, err := h.do(ctx, "POST", h.threadsURL(), reqData, &resData)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating comment")
+	}
+
+	if len(resData.Comments) == 0 {
+		return nil, errors.New("Error creating comment: thread was created without a comment")
+	}
+
+	return &azureReposComment{
+		id:           resData.Comments[0].ID,
+		threadID:     resData.ID,
+		body:         resData.Comments[0].Content,
+		createdAt:    resData.Comments[0].PublishedDate,
+		updatedAt:    resData.Comments[0].LastUpdatedDate,
+		url:          h.threadRefURL(resData.ID),
+		threadStatus: resData.Status,
+	}, nil
+}
+
+// CallUpdateComment calls the Azure DevOps API to update the body of a comment on the pull request.
+func (h *azureReposPRHandler) CallUpdateComment(ctx context.Context, comment Comment, body string) error {
+	c := comment.(*azureReposComment)
+
+	reqURL := fmt.Sprintf("%s/%d/comments/%d", h.threadsURL(), c.threadID, c.id)
+
+	_, err := h.do(ctx, "PATCH", reqURL, map[string]interface{}{"content": body}, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error updating comment")
+	}
+
+	return nil
+}
+
+// CallDeleteComment calls the Azure DevOps API to delete the pull request comment.
+func (h *azureReposPRHandler) CallDeleteComment(ctx context.Context, comment Comment) error {
+	c := comment.(*azureReposComment)
+
+	reqURL := fmt.Sprintf("%s/%d/comments/%d", h.threadsURL(), c.threadID, c.id)
+
+	_, err := h.do(ctx, "DELETE", reqURL, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting comment")
+	}
+
+	return nil
+}
+
+// CallHideComment calls the Azure DevOps API to hide the pull request comment.
+// Azure Repos doesn't support hiding individual comments, so instead the thread
+// containing the comment is closed, which collapses it in the UI.
+func (h *azureReposPRHandler) CallHideComment(ctx context.Context, comment Comment) error {
+	c := comment.(*azureReposComment)
+
+	reqURL := fmt.Sprintf("%s/%d", h.threadsURL(), c.threadID)
+
+	_, err := h.do(ctx, "PATCH", reqURL, map[string]interface{}{"status": "closed"}, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error hiding comment")
+	}
+
+	c.threadStatus = "closed"
+
+	return nil
+}
+
+// CallGetAuthenticatedUser is not supported by Azure Repos.
+func (h *azureReposPRHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return "", errors.New("Not implemented")
+}
+
+// MaxBodyLength returns the maximum number of characters Azure Repos allows in a comment body.
+func (h *azureReposPRHandler) MaxBodyLength() int {
+	return azureReposMaxBodyLength
+}
+
+func init() {
+	// Here we register the platform handlers against the platform and target type they support
+	registerPlatformHandler("azure-repos", "pull-request", newAzureReposPRHandler)
+}
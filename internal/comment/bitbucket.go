@@ -0,0 +1,502 @@
+package comment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// bitbucketComment represents a comment on a Bitbucket Cloud pull request or commit. It
+// implements the Comment interface.
+type bitbucketComment struct {
+	id        int
+	body      string
+	createdAt time.Time
+	updatedAt time.Time
+	url       string
+}
+
+// ID returns the ID of the comment.
+func (c *bitbucketComment) ID() string {
+	return strconv.Itoa(c.id)
+}
+
+// Body returns the body of the comment
+func (c *bitbucketComment) Body() string {
+	return c.body
+}
+
+// Ref returns the reference to the comment. For Bitbucket this is a URL to the
+// HTML page of the comment.
+func (c *bitbucketComment) Ref() string {
+	return c.url
+}
+
+// Less compares the comment to another comment and returns true if this
+// comment should be sorted before the other comment.
+func (c *bitbucketComment) Less(other Comment) bool {
+	j := other.(*bitbucketComment)
+
+	if !c.createdAt.Equal(j.createdAt) {
+		return c.createdAt.Before(j.createdAt)
+	}
+
+	return c.id < j.id
+}
+
+// IsHidden always returns false for Bitbucket since Bitbucket doesn't have a
+// feature for hiding comments.
+func (c *bitbucketComment) IsHidden() bool {
+	return false
+}
+
+// CreatedAt returns the time the comment was created.
+func (c *bitbucketComment) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// UpdatedAt returns the time the comment was last updated.
+func (c *bitbucketComment) UpdatedAt() time.Time {
+	return c.updatedAt
+}
+
+// Author always returns an empty string since filtering by author is not
+// supported for Bitbucket.
+func (c *bitbucketComment) Author() string {
+	return ""
+}
+
+// Version always returns an empty string since comment versions are not
+// supported for Bitbucket.
+func (c *bitbucketComment) Version() string {
+	return ""
+}
+
+// Metadata returns the metadata embedded in the comment, or nil if it has none.
+func (c *bitbucketComment) Metadata() *Metadata {
+	return parseMetadata(c.body)
+}
+
+// BitbucketExtra contains any extra inputs that can be passed to the Bitbucket comment handlers.
+type BitbucketExtra struct {
+	// APIURL is the URL of the Bitbucket API. If not set, the default
+	// Bitbucket Cloud API URL will be used.
+	APIURL string
+	// Token is the Bitbucket API token. This can either be a repository/workspace
+	// access token, or a username and app password in the form username:app-password.
+	Token string
+}
+
+// bitbucketAPIComment is the structure of a comment returned by the Bitbucket Cloud API.
+type bitbucketAPIComment struct {
+	ID      int `json:"id"`
+	Content struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+	Deleted   bool      `json:"deleted"`
+	Links     struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+// toComment converts the API comment into a bitbucketComment.
+func (c bitbucketAPIComment) toComment() *bitbucketComment {
+	return &bitbucketComment{
+		id:        c.ID,
+		body:      c.Content.Raw,
+		createdAt: c.CreatedOn,
+		updatedAt: c.UpdatedOn,
+		url:       c.Links.HTML.Href,
+	}
+}
+
+// bitbucketAPIClient is a minimal client for the Bitbucket Cloud REST API.
+type bitbucketAPIClient struct {
+	httpClient *http.Client
+	apiURL     string
+	token      string
+}
+
+// newBitbucketAPIClient creates a client for the Bitbucket Cloud REST API.
+// If the apiURL is not set, the default Bitbucket Cloud API URL will be used.
+func newBitbucketAPIClient(ctx context.Context, token string, apiURL string) (*bitbucketAPIClient, error) {
+	if apiURL == "" {
+		apiURL = "https://api.bitbucket.org"
+	}
+
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing API URL")
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	// Add the API version to the path if it doesn't exist
+	if !strings.HasSuffix(u.Path, "/2.0") {
+		u.Path += "/2.0"
+	}
+
+	return &bitbucketAPIClient{
+		httpClient: http.DefaultClient,
+		apiURL:     u.String(),
+		token:      token,
+	}, nil
+}
+
+// bitbucketResponseError is returned when the Bitbucket API responds with an unexpected status.
+type bitbucketResponseError struct {
+	statusCode int
+	status     string
+}
+
+// Error returns the error message.
+func (e *bitbucketResponseError) Error() string {
+	return fmt.Sprintf("Unexpected response: %s", e.status)
+}
+
+// isBitbucketConflict returns true if the error is a 409 Conflict response, which Bitbucket
+// Server returns if the comment version sent with a request is not the latest version.
+func isBitbucketConflict(err error) bool {
+	e, ok := errors.Cause(err).(*bitbucketResponseError)
+	return ok && e.statusCode == http.StatusConflict
+}
+
+// do sends a request to the Bitbucket API and unmarshals the response into resData
+// if it is not nil. It returns an error if the response status is not the expected status.
+func (c *bitbucketAPIClient) do(ctx context.Context, method string, url string, reqData interface{}, expectedStatus int, resData interface{}) error {
+	var reqBody io.Reader
+	if reqData != nil {
+		b, err := json.Marshal(reqData)
+		if err != nil {
+			return errors.Wrap(err, "Error marshaling request body")
+		}
+		reqBody = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return errors.Wrap(err, "Error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	// App passwords are passed as username:app-password and use basic auth,
+	// access tokens use bearer auth.
+	if parts := strings.SplitN(c.token, ":", 2); len(parts) == 2 {
+		req.SetBasicAuth(parts[0], parts[1])
+	} else if c.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "Error sending request")
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	if res.StatusCode != expectedStatus {
+		return &bitbucketResponseError{statusCode: res.StatusCode, status: res.Status}
+	}
+
+	if resData == nil {
+		return nil
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrap(err, "Error reading response body")
+	}
+
+	err = json.Unmarshal(resBody, resData)
+	if err != nil {
+		return errors.Wrap(err, "Error unmarshaling response body")
+	}
+
+	return nil
+}
+
+// findComments gets the comments from all pages starting at the given URL.
+// Comments that have been deleted are skipped.
+func (c *bitbucketAPIClient) findComments(ctx context.Context, url string) ([]Comment, error) {
+	var allComments []Comment
+
+	for url != "" {
+		var resData struct {
+			Values []bitbucketAPIComment `json:"values"`
+			Next   string                `json:"next"`
+		}
+
+		err := c.do(ctx, "GET", url, nil, http.StatusOK, &resData)
+		if err != nil {
+			return []Comment{}, errors.Wrap(err, "Error getting comments")
+		}
+
+		for _, comment := range resData.Values {
+			if comment.Deleted {
+				continue
+			}
+			allComments = append(allComments, comment.toComment())
+		}
+
+		url = resData.Next
+	}
+
+	return allComments, nil
+}
+
+// createComment creates a comment at the given URL.
+func (c *bitbucketAPIClient) createComment(ctx context.Context, url string, body string) (Comment, error) {
+	var resData bitbucketAPIComment
+
+	err := c.do(ctx, "POST", url, bitbucketCommentRequest(body), http.StatusCreated, &resData)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating comment")
+	}
+
+	return resData.toComment(), nil
+}
+
+// updateComment updates the comment at the given URL.
+func (c *bitbucketAPIClient) updateComment(ctx context.Context, url string, body string) error {
+	err := c.do(ctx, "PUT", url, bitbucketCommentRequest(body), http.StatusOK, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error updating comment")
+	}
+
+	return nil
+}
+
+// deleteComment deletes the comment at the given URL.
+func (c *bitbucketAPIClient) deleteComment(ctx context.Context, url string) error {
+	err := c.do(ctx, "DELETE", url, nil, http.StatusNoContent, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting comment")
+	}
+
+	return nil
+}
+
+// bitbucketCommentRequest returns the request data for creating or updating a comment.
+func bitbucketCommentRequest(body string) map[string]interface{} {
+	return map[string]interface{}{
+		"content": map[string]interface{}{
+			"raw": body,
+		},
+	}
+}
+
+// splitBitbucketProject parses a Bitbucket project string into its workspace and repo slug parts.
+func splitBitbucketProject(project string) (string, string, error) {
+	parts := strings.SplitN(project, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("Invalid Bitbucket repository name: %s, expecting workspace/repo", project)
+	}
+	return parts[0], parts[1], nil
+}
+
+// bitbucketMaxBodyLength is the maximum number of characters Bitbucket allows in a comment body.
+// Bitbucket doesn't document the limit, so this is a conservative value that can be overridden
+// with CommentHandler.MaxCommentLength.
+const bitbucketMaxBodyLength = 32768
+
+// bitbucketPRHandler is a PlatformHandler for Bitbucket Cloud pull requests. It
+// implements the PlatformHandler interface and contains the functions
+// for finding, creating, updating, deleting comments on Bitbucket pull requests.
+type bitbucketPRHandler struct {
+	client    *bitbucketAPIClient
+	workspace string
+	repo      string
+	prNumber  int
+}
+
+// newBitbucketPRHandler creates a new PlatformHandler for Bitbucket Cloud pull requests.
+func newBitbucketPRHandler(ctx context.Context, project string, targetRef string, extra interface{}) (PlatformHandler, error) {
+	bitbucketExtra, ok := extra.(BitbucketExtra)
+	if !ok {
+		return nil, errors.New("Invalid extra")
+	}
+
+	workspace, repo, err := splitBitbucketProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	prNumber, err := strconv.Atoi(targetRef)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing targetRef as pull request number")
+	}
+
+	client, err := newBitbucketAPIClient(ctx, bitbucketExtra.Token, bitbucketExtra.APIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &bitbucketPRHandler{
+		client:    client,
+		workspace: workspace,
+		repo:      repo,
+		prNumber:  prNumber,
+	}
+
+	return h, nil
+}
+
+// commentsURL returns the API URL for the pull request comments.
+func (h *bitbucketPRHandler) commentsURL() string {
+	return fmt.Sprintf(
+		"%s/repositories/%s/%s/pullrequests/%d/comments",
+		h.client.apiURL, url.PathEscape(h.workspace), url.PathEscape(h.repo), h.prNumber,
+	)
+}
+
+// CallFindComments calls the Bitbucket API to find all the pull request comments.
+func (h *bitbucketPRHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
+	allComments, err := h.client.findComments(ctx, fmt.Sprintf("%s?pagelen=100", h.commentsURL()))
+	if err != nil {
+		return []Comment{}, err
+	}
+
+	return allComments, nil
+}
+
+// CallCreateComment calls the Bitbucket API to create a new comment on the pull request.
+func (h *bitbucketPRHandler) CallCreateComment(ctx context.Context, body string) (Comment, error) {
+	return h.client.createComment(ctx, h.commentsURL(), body)
+}
+
+// CallUpdateComment calls the Bitbucket API to update the body of a comment on the pull request.
+func (h *bitbucketPRHandler) CallUpdateComment(ctx context.Context, comment Comment, body string) error {
+	url := fmt.Sprintf("%s/%d", h.commentsURL(), comment.(*bitbucketComment).id)
+	return h.client.updateComment(ctx, url, body)
+}
+
+// CallDeleteComment calls the Bitbucket API to delete the pull request comment.
+func (h *bitbucketPRHandler) CallDeleteComment(ctx context.Context, comment Comment) error {
+	url := fmt.Sprintf("%s/%d", h.commentsURL(), comment.(*bitbucketComment).id)
+	return h.client.deleteComment(ctx, url)
+}
+
+// CallHideComment is not supported by Bitbucket.
+func (h *bitbucketPRHandler) CallHideComment(ctx context.Context, comment Comment) error {
+	return errors.New("Not implemented")
+}
+
+// CallGetAuthenticatedUser is not supported by Bitbucket.
+func (h *bitbucketPRHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return "", errors.New("Not implemented")
+}
+
+// MaxBodyLength returns the maximum number of characters Bitbucket allows in a comment body.
+func (h *bitbucketPRHandler) MaxBodyLength() int {
+	return bitbucketMaxBodyLength
+}
+
+// bitbucketCommitHandler is a PlatformHandler for Bitbucket Cloud commits. It
+// implements the PlatformHandler interface and contains the functions
+// for finding, creating, updating, deleting comments on Bitbucket commits.
+type bitbucketCommitHandler struct {
+	client    *bitbucketAPIClient
+	workspace string
+	repo      string
+	commitSHA string
+}
+
+// newBitbucketCommitHandler creates a new PlatformHandler for Bitbucket Cloud commits.
+func newBitbucketCommitHandler(ctx context.Context, project string, targetRef string, extra interface{}) (PlatformHandler, error) {
+	bitbucketExtra, ok := extra.(BitbucketExtra)
+	if !ok {
+		return nil, errors.New("Invalid extra")
+	}
+
+	workspace, repo, err := splitBitbucketProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newBitbucketAPIClient(ctx, bitbucketExtra.Token, bitbucketExtra.APIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &bitbucketCommitHandler{
+		client:    client,
+		workspace: workspace,
+		repo:      repo,
+		commitSHA: targetRef,
+	}
+
+	return h, nil
+}
+
+// commentsURL returns the API URL for the commit comments.
+func (h *bitbucketCommitHandler) commentsURL() string {
+	return fmt.Sprintf(
+		"%s/repositories/%s/%s/commit/%s/comments",
+		h.client.apiURL, url.PathEscape(h.workspace), url.PathEscape(h.repo), h.commitSHA,
+	)
+}
+
+// CallFindComments calls the Bitbucket API to find all the commit comments.
+func (h *bitbucketCommitHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
+	allComments, err := h.client.findComments(ctx, fmt.Sprintf("%s?pagelen=100", h.commentsURL()))
+	if err != nil {
+		return []Comment{}, err
+	}
+
+	return allComments, nil
+}
+
+// CallCreateComment calls the Bitbucket API to create a new comment on the commit.
+func (h *bitbucketCommitHandler) CallCreateComment(ctx context.Context, body string) (Comment, error) {
+	return h.client.createComment(ctx, h.commentsURL(), body)
+}
+
+// CallUpdateComment calls the Bitbucket API to update the body of a comment on the commit.
+func (h *bitbucketCommitHandler) CallUpdateComment(ctx context.Context, comment Comment, body string) error {
+	url := fmt.Sprintf("%s/%d", h.commentsURL(), comment.(*bitbucketComment).id)
+	return h.client.updateComment(ctx, url, body)
+}
+
+// CallDeleteComment calls the Bitbucket API to delete the commit comment.
+func (h *bitbucketCommitHandler) CallDeleteComment(ctx context.Context, comment Comment) error {
+	url := fmt.Sprintf("%s/%d", h.commentsURL(), comment.(*bitbucketComment).id)
+	return h.client.deleteComment(ctx, url)
+}
+
+// CallHideComment is not supported by Bitbucket.
+func (h *bitbucketCommitHandler) CallHideComment(ctx context.Context, comment Comment) error {
+	return errors.New("Not implemented")
+}
+
+// CallGetAuthenticatedUser is not supported by Bitbucket.
+func (h *bitbucketCommitHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return "", errors.New("Not implemented")
+}
+
+// MaxBodyLength returns the maximum number of characters Bitbucket allows in a comment body.
+func (h *bitbucketCommitHandler) MaxBodyLength() int {
+	return bitbucketMaxBodyLength
+}
+
+func init() {
+	// Here we register the platform handlers against the platform and target type they support
+	registerPlatformHandler("bitbucket", "pull-request", newBitbucketPRHandler)
+	registerPlatformHandler("bitbucket", "commit", newBitbucketCommitHandler)
+}
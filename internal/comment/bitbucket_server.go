@@ -0,0 +1,491 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// bitbucketServerComment represents a comment on a Bitbucket Server pull request or commit.
+// It implements the Comment interface.
+type bitbucketServerComment struct {
+	id        int
+	version   int
+	body      string
+	createdAt time.Time
+	updatedAt time.Time
+	url       string
+}
+
+// ID returns the ID of the comment.
+func (c *bitbucketServerComment) ID() string {
+	return strconv.Itoa(c.id)
+}
+
+// Body returns the body of the comment
+func (c *bitbucketServerComment) Body() string {
+	return c.body
+}
+
+// Ref returns the reference to the comment. For Bitbucket Server this is a URL to the
+// HTML page of the comment.
+func (c *bitbucketServerComment) Ref() string {
+	return c.url
+}
+
+// Less compares the comment to another comment and returns true if this
+// comment should be sorted before the other comment.
+func (c *bitbucketServerComment) Less(other Comment) bool {
+	j := other.(*bitbucketServerComment)
+
+	if !c.createdAt.Equal(j.createdAt) {
+		return c.createdAt.Before(j.createdAt)
+	}
+
+	return c.id < j.id
+}
+
+// IsHidden always returns false for Bitbucket Server since Bitbucket Server doesn't have a
+// feature for hiding comments.
+func (c *bitbucketServerComment) IsHidden() bool {
+	return false
+}
+
+// CreatedAt returns the time the comment was created.
+func (c *bitbucketServerComment) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// UpdatedAt returns the time the comment was last updated.
+func (c *bitbucketServerComment) UpdatedAt() time.Time {
+	return c.updatedAt
+}
+
+// Author always returns an empty string since filtering by author is not
+// supported for Bitbucket Server.
+func (c *bitbucketServerComment) Author() string {
+	return ""
+}
+
+// Version returns the version of the comment, which Bitbucket Server increments
+// whenever the comment is edited.
+func (c *bitbucketServerComment) Version() string {
+	return strconv.Itoa(c.version)
+}
+
+// Metadata returns the metadata embedded in the comment, or nil if it has none.
+func (c *bitbucketServerComment) Metadata() *Metadata {
+	return parseMetadata(c.body)
+}
+
+// BitbucketServerExtra contains any extra inputs that can be passed to the Bitbucket Server comment handlers.
+type BitbucketServerExtra struct {
+	// ServerURL is the URL of the Bitbucket Server or Data Center instance.
+	ServerURL string
+	// Token is the Bitbucket Server API token. This can either be a personal/HTTP
+	// access token, or a username and password in the form username:password.
+	Token string
+}
+
+// bitbucketServerAPIComment is the structure of a comment returned by the Bitbucket Server API.
+type bitbucketServerAPIComment struct {
+	ID          int    `json:"id"`
+	Version     int    `json:"version"`
+	Text        string `json:"text"`
+	CreatedDate int64  `json:"createdDate"`
+	UpdatedDate int64  `json:"updatedDate"`
+}
+
+// toComment converts the API comment into a bitbucketServerComment with the given URL.
+func (c bitbucketServerAPIComment) toComment(url string) *bitbucketServerComment {
+	return &bitbucketServerComment{
+		id:        c.ID,
+		version:   c.Version,
+		body:      c.Text,
+		createdAt: time.Unix(0, c.CreatedDate*int64(time.Millisecond)),
+		updatedAt: time.Unix(0, c.UpdatedDate*int64(time.Millisecond)),
+		url:       url,
+	}
+}
+
+// bitbucketServerPage contains the paging fields returned by the Bitbucket Server API.
+type bitbucketServerPage struct {
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+// newBitbucketServerAPIClient creates a client for the Bitbucket Server REST API.
+func newBitbucketServerAPIClient(ctx context.Context, token string, serverURL string) (*bitbucketAPIClient, error) {
+	if serverURL == "" {
+		return nil, errors.New("Bitbucket Server URL is required")
+	}
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing server URL")
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/rest/api/1.0"
+
+	return &bitbucketAPIClient{
+		httpClient: http.DefaultClient,
+		apiURL:     u.String(),
+		token:      token,
+	}, nil
+}
+
+// splitBitbucketServerProject parses a Bitbucket Server project string into its project key and repo slug parts.
+func splitBitbucketServerProject(project string) (string, string, error) {
+	parts := strings.SplitN(project, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("Invalid Bitbucket Server repository name: %s, expecting project/repo", project)
+	}
+	return parts[0], parts[1], nil
+}
+
+// updateBitbucketServerComment calls the Bitbucket Server API to update the comment at the
+// given URL. The comment version is sent so the update is rejected if the comment has
+// been modified since it was retrieved, in which case errCommentChanged is returned.
+func updateBitbucketServerComment(ctx context.Context, client *bitbucketAPIClient, url string, comment *bitbucketServerComment, body string) error {
+	reqData := map[string]interface{}{
+		"text":    body,
+		"version": comment.version,
+	}
+
+	var resData bitbucketServerAPIComment
+
+	err := client.do(ctx, "PUT", url, reqData, http.StatusOK, &resData)
+	if isBitbucketConflict(err) {
+		return errCommentChanged
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Error updating comment")
+	}
+
+	comment.version = resData.Version
+	comment.body = resData.Text
+
+	return nil
+}
+
+// deleteBitbucketServerComment calls the Bitbucket Server API to delete the comment at the
+// given URL. The comment version is sent so the delete is rejected if the comment has
+// been modified since it was retrieved, in which case errCommentChanged is returned.
+func deleteBitbucketServerComment(ctx context.Context, client *bitbucketAPIClient, url string, comment *bitbucketServerComment) error {
+	err := client.deleteComment(ctx, fmt.Sprintf("%s?version=%d", url, comment.version))
+	if isBitbucketConflict(err) {
+		return errCommentChanged
+	}
+
+	return err
+}
+
+// bitbucketServerMaxBodyLength is the maximum number of characters Bitbucket Server allows in a comment body.
+// The limit isn't documented and can be changed by the server admin, so this is a conservative value
+// that can be overridden with CommentHandler.MaxCommentLength.
+const bitbucketServerMaxBodyLength = 32768
+
+// bitbucketServerPRHandler is a PlatformHandler for Bitbucket Server pull requests. It
+// implements the PlatformHandler interface and contains the functions
+// for finding, creating, updating, deleting comments on Bitbucket Server pull requests.
+type bitbucketServerPRHandler struct {
+	client     *bitbucketAPIClient
+	serverURL  string
+	projectKey string
+	repo       string
+	prNumber   int
+}
+
+// newBitbucketServerPRHandler creates a new PlatformHandler for Bitbucket Server pull requests.
+func newBitbucketServerPRHandler(ctx context.Context, project string, targetRef string, extra interface{}) (PlatformHandler, error) {
+	bitbucketServerExtra, ok := extra.(BitbucketServerExtra)
+	if !ok {
+		return nil, errors.New("Invalid extra")
+	}
+
+	projectKey, repo, err := splitBitbucketServerProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	prNumber, err := strconv.Atoi(targetRef)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing targetRef as pull request number")
+	}
+
+	client, err := newBitbucketServerAPIClient(ctx, bitbucketServerExtra.Token, bitbucketServerExtra.ServerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &bitbucketServerPRHandler{
+		client:     client,
+		serverURL:  strings.TrimSuffix(bitbucketServerExtra.ServerURL, "/"),
+		projectKey: projectKey,
+		repo:       repo,
+		prNumber:   prNumber,
+	}
+
+	return h, nil
+}
+
+// prURL returns the API URL for the pull request.
+func (h *bitbucketServerPRHandler) prURL() string {
+	return fmt.Sprintf(
+		"%s/projects/%s/repos/%s/pull-requests/%d",
+		h.client.apiURL, url.PathEscape(h.projectKey), url.PathEscape(h.repo), h.prNumber,
+	)
+}
+
+// commentRefURL returns the URL to the HTML page of the comment.
+func (h *bitbucketServerPRHandler) commentRefURL(id int) string {
+	return fmt.Sprintf(
+		"%s/projects/%s/repos/%s/pull-requests/%d/overview?commentId=%d",
+		h.serverURL, url.PathEscape(h.projectKey), url.PathEscape(h.repo), h.prNumber, id,
+	)
+}
+
+// CallFindComments calls the Bitbucket Server API to find all the pull request comments.
+//
+// Bitbucket Server doesn't have an endpoint for listing all the general comments on
+// a pull request, so the comments are retrieved from the pull request activities.
+func (h *bitbucketServerPRHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
+	comments := map[int]*bitbucketServerComment{}
+	deletedIDs := map[int]bool{}
+	var ids []int
+
+	start := 0
+
+	// Get activities from all pages.
+	for {
+		var resData struct {
+			bitbucketServerPage
+			Values []struct {
+				Action        string                    `json:"action"`
+				CommentAction string                    `json:"commentAction"`
+				Comment       bitbucketServerAPIComment `json:"comment"`
+				CommentAnchor *struct{}                 `json:"commentAnchor"`
+			} `json:"values"`
+		}
+
+		url := fmt.Sprintf("%s/activities?limit=100&start=%d", h.prURL(), start)
+
+		err := h.client.do(ctx, "GET", url, nil, http.StatusOK, &resData)
+		if err != nil {
+			return []Comment{}, errors.Wrap(err, "Error getting comments")
+		}
+
+		for _, activity := range resData.Values {
+			// Skip any non-comment activities and any inline comments
+			if activity.Action != "COMMENTED" || activity.CommentAnchor != nil {
+				continue
+			}
+
+			id := activity.Comment.ID
+
+			if activity.CommentAction == "DELETED" {
+				deletedIDs[id] = true
+				continue
+			}
+
+			if _, ok := comments[id]; !ok {
+				ids = append(ids, id)
+				comments[id] = activity.Comment.toComment(h.commentRefURL(id))
+			}
+		}
+
+		if resData.IsLastPage {
+			break
+		}
+		start = resData.NextPageStart
+	}
+
+	var allComments []Comment
+	for _, id := range ids {
+		if deletedIDs[id] {
+			continue
+		}
+		allComments = append(allComments, comments[id])
+	}
+
+	return allComments, nil
+}
+
+// CallCreateComment calls the Bitbucket Server API to create a new comment on the pull request.
+func (h *bitbucketServerPRHandler) CallCreateComment(ctx context.Context, body string) (Comment, error) {
+	var resData bitbucketServerAPIComment
+
+	err := h.client.do(ctx, "POST", fmt.Sprintf("%s/comments", h.prURL()), map[string]interface{}{"text": body}, http.StatusCreated, &resData)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating comment")
+	}
+
+	return resData.toComment(h.commentRefURL(resData.ID)), nil
+}
+
+// CallUpdateComment calls the Bitbucket Server API to update the body of a comment on the pull request.
+func (h *bitbucketServerPRHandler) CallUpdateComment(ctx context.Context, comment Comment, body string) error {
+	c := comment.(*bitbucketServerComment)
+	return updateBitbucketServerComment(ctx, h.client, fmt.Sprintf("%s/comments/%d", h.prURL(), c.id), c, body)
+}
+
+// CallDeleteComment calls the Bitbucket Server API to delete the pull request comment.
+func (h *bitbucketServerPRHandler) CallDeleteComment(ctx context.Context, comment Comment) error {
+	c := comment.(*bitbucketServerComment)
+	return deleteBitbucketServerComment(ctx, h.client, fmt.Sprintf("%s/comments/%d", h.prURL(), c.id), c)
+}
+
+// CallHideComment is not supported by Bitbucket Server.
+func (h *bitbucketServerPRHandler) CallHideComment(ctx context.Context, comment Comment) error {
+	return errors.New("Not implemented")
+}
+
+// CallGetAuthenticatedUser is not supported by Bitbucket Server.
+func (h *bitbucketServerPRHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return "", errors.New("Not implemented")
+}
+
+// MaxBodyLength returns the maximum number of characters Bitbucket Server allows in a comment body.
+func (h *bitbucketServerPRHandler) MaxBodyLength() int {
+	return bitbucketServerMaxBodyLength
+}
+
+// bitbucketServerCommitHandler is a PlatformHandler for Bitbucket Server commits. It
+// implements the PlatformHandler interface and contains the functions
+// for finding, creating, updating, deleting comments on Bitbucket Server commits.
+type bitbucketServerCommitHandler struct {
+	client     *bitbucketAPIClient
+	serverURL  string
+	projectKey string
+	repo       string
+	commitSHA  string
+}
+
+// newBitbucketServerCommitHandler creates a new PlatformHandler for Bitbucket Server commits.
+func newBitbucketServerCommitHandler(ctx context.Context, project string, targetRef string, extra interface{}) (PlatformHandler, error) {
+	bitbucketServerExtra, ok := extra.(BitbucketServerExtra)
+	if !ok {
+		return nil, errors.New("Invalid extra")
+	}
+
+	projectKey, repo, err := splitBitbucketServerProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newBitbucketServerAPIClient(ctx, bitbucketServerExtra.Token, bitbucketServerExtra.ServerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &bitbucketServerCommitHandler{
+		client:     client,
+		serverURL:  strings.TrimSuffix(bitbucketServerExtra.ServerURL, "/"),
+		projectKey: projectKey,
+		repo:       repo,
+		commitSHA:  targetRef,
+	}
+
+	return h, nil
+}
+
+// commentsURL returns the API URL for the commit comments.
+func (h *bitbucketServerCommitHandler) commentsURL() string {
+	return fmt.Sprintf(
+		"%s/projects/%s/repos/%s/commits/%s/comments",
+		h.client.apiURL, url.PathEscape(h.projectKey), url.PathEscape(h.repo), h.commitSHA,
+	)
+}
+
+// commentRefURL returns the URL to the HTML page of the comment.
+func (h *bitbucketServerCommitHandler) commentRefURL(id int) string {
+	return fmt.Sprintf(
+		"%s/projects/%s/repos/%s/commits/%s?commentId=%d",
+		h.serverURL, url.PathEscape(h.projectKey), url.PathEscape(h.repo), h.commitSHA, id,
+	)
+}
+
+// CallFindComments calls the Bitbucket Server API to find all the commit comments.
+func (h *bitbucketServerCommitHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
+	var allComments []Comment
+
+	start := 0
+
+	// Get comments from all pages.
+	for {
+		var resData struct {
+			bitbucketServerPage
+			Values []bitbucketServerAPIComment `json:"values"`
+		}
+
+		url := fmt.Sprintf("%s?limit=100&start=%d", h.commentsURL(), start)
+
+		err := h.client.do(ctx, "GET", url, nil, http.StatusOK, &resData)
+		if err != nil {
+			return []Comment{}, errors.Wrap(err, "Error getting comments")
+		}
+
+		for _, comment := range resData.Values {
+			allComments = append(allComments, comment.toComment(h.commentRefURL(comment.ID)))
+		}
+
+		if resData.IsLastPage {
+			break
+		}
+		start = resData.NextPageStart
+	}
+
+	return allComments, nil
+}
+
+// CallCreateComment calls the Bitbucket Server API to create a new comment on the commit.
+func (h *bitbucketServerCommitHandler) CallCreateComment(ctx context.Context, body string) (Comment, error) {
+	var resData bitbucketServerAPIComment
+
+	err := h.client.do(ctx, "POST", h.commentsURL(), map[string]interface{}{"text": body}, http.StatusCreated, &resData)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating comment")
+	}
+
+	return resData.toComment(h.commentRefURL(resData.ID)), nil
+}
+
+// CallUpdateComment calls the Bitbucket Server API to update the body of a comment on the commit.
+func (h *bitbucketServerCommitHandler) CallUpdateComment(ctx context.Context, comment Comment, body string) error {
+	c := comment.(*bitbucketServerComment)
+	return updateBitbucketServerComment(ctx, h.client, fmt.Sprintf("%s/%d", h.commentsURL(), c.id), c, body)
+}
+
+// CallDeleteComment calls the Bitbucket Server API to delete the commit comment.
+func (h *bitbucketServerCommitHandler) CallDeleteComment(ctx context.Context, comment Comment) error {
+	c := comment.(*bitbucketServerComment)
+	return deleteBitbucketServerComment(ctx, h.client, fmt.Sprintf("%s/%d", h.commentsURL(), c.id), c)
+}
+
+// CallHideComment is not supported by Bitbucket Server.
+func (h *bitbucketServerCommitHandler) CallHideComment(ctx context.Context, comment Comment) error {
+	return errors.New("Not implemented")
+}
+
+// CallGetAuthenticatedUser is not supported by Bitbucket Server.
+func (h *bitbucketServerCommitHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return "", errors.New("Not implemented")
+}
+
+// MaxBodyLength returns the maximum number of characters Bitbucket Server allows in a comment body.
+func (h *bitbucketServerCommitHandler) MaxBodyLength() int {
+	return bitbucketServerMaxBodyLength
+}
+
+func init() {
+	// Here we register the platform handlers against the platform and target type they support
+	registerPlatformHandler("bitbucket-server", "pull-request", newBitbucketServerPRHandler)
+	registerPlatformHandler("bitbucket-server", "commit", newBitbucketServerCommitHandler)
+}
@@ -0,0 +1,69 @@
+package comment
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestBitbucketServerCommentConflict(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantErr   bool
+		wantRetry bool
+	}{
+		{
+			name:   "updated",
+			status: http.StatusOK,
+		},
+		{
+			name:      "version conflict",
+			status:    http.StatusConflict,
+			wantErr:   true,
+			wantRetry: true,
+		},
+		{
+			name:    "server error",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.status
+				if status == http.StatusOK && r.Method == "DELETE" {
+					status = http.StatusNoContent
+				}
+
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					fmt.Fprint(w, `{"id": 1, "version": 2, "text": "new"}`)
+				}
+			}))
+			defer server.Close()
+
+			client, err := newBitbucketServerAPIClient(testContext(), "", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			url := client.apiURL + "/comments/1"
+
+			err = updateBitbucketServerComment(testContext(), client, url, &bitbucketServerComment{id: 1, version: 1}, "new")
+			if (err != nil) != tt.wantErr || (errors.Cause(err) == errCommentChanged) != tt.wantRetry {
+				t.Errorf("updateBitbucketServerComment() error = %v", err)
+			}
+
+			err = deleteBitbucketServerComment(testContext(), client, url, &bitbucketServerComment{id: 1, version: 1})
+			if (err != nil) != tt.wantErr || (errors.Cause(err) == errCommentChanged) != tt.wantRetry {
+				t.Errorf("deleteBitbucketServerComment() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package comment
+
+import (
+	"context"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// maxUpdateAttempts is the number of times an update is attempted if the comment
+// is changed by another job between reading and updating it.
+const maxUpdateAttempts = 3
+
+// errCommentChanged is returned when a comment was changed or created by another
+// job while it was being updated, so the update has to be applied again to the
+// latest comment.
+var errCommentChanged = errors.New("Comment was changed by another job while updating it")
+
+// retryOnConflict calls f until it doesn't return errCommentChanged, up to maxUpdateAttempts
+// times. f must read the latest comment each time so the update is applied to it. Each
+// attempt adds its actions to a new result, so the returned result only has the actions of
+// the final attempt, apart from any comments deleted by the earlier attempts.
+func retryOnConflict(ctx context.Context, f func(result *Result) error) (*Result, error) {
+	var deleted []CommentResult
+
+	for attempt := 1; attempt <= maxUpdateAttempts; attempt++ {
+		result := newResult()
+
+		err := f(result)
+		if errors.Cause(err) != errCommentChanged {
+			if err != nil {
+				return nil, err
+			}
+
+			result.Comments = append(deleted, result.Comments...)
+			return result, nil
+		}
+
+		// Deleting a comment can't be undone by retrying, so it's still reported
+		for _, c := range result.Comments {
+			if c.Action == ActionDeleted {
+				deleted = append(deleted, c)
+			}
+		}
+
+		if attempt < maxUpdateAttempts {
+			log.Ctx(ctx).Warn().Msgf("Comment was changed by another job while updating it, retrying (attempt %d of %d)", attempt+1, maxUpdateAttempts)
+		}
+	}
+
+	return nil, errCommentChanged
+}
+
+// checkUnchanged fetches the comments again and returns errCommentChanged if any
+// of the given comments has been changed or deleted since it was read. Comments
+// without a version can't be checked so they are assumed to be unchanged.
+func (h *CommentHandler) checkUnchanged(ctx context.Context, comments []Comment) error {
+	versions := map[string]string{}
+	for _, comment := range comments {
+		if comment.Version() != "" {
+			versions[comment.Ref()] = comment.Version()
+		}
+	}
+
+	if len(versions) == 0 {
+		return nil
+	}
+
+	currentComments, err := h.PlatformHandler.CallFindComments(ctx)
+	if err != nil {
+		return err
+	}
+
+	found := 0
+	for _, comment := range currentComments {
+		version, ok := versions[comment.Ref()]
+		if !ok {
+			continue
+		}
+
+		if comment.Version() != version {
+			log.Ctx(ctx).Debug().Msgf("Comment %s has changed since it was read", comment.Ref())
+			return errCommentChanged
+		}
+
+		found++
+	}
+
+	if found != len(versions) {
+		log.Ctx(ctx).Debug().Msg("Comment has been deleted since it was read")
+		return errCommentChanged
+	}
+
+	return nil
+}
+
+// removeDuplicates checks if another job created a matching comment at the same time
+// as the given comments were created. Every job keeps the comment that would be returned
+// by LatestMatchingComment, so if that isn't one of the given comments they are deleted
+// and errCommentChanged is returned so the update is applied to the kept comment instead.
+func (h *CommentHandler) removeDuplicates(ctx context.Context, result *Result, created []Comment) error {
+	matchingComments, err := h.matchingComments(ctx)
+	if err != nil {
+		return err
+	}
+
+	refs := map[string]bool{}
+	for _, comment := range matchingComments {
+		refs[comment.Ref()] = true
+	}
+
+	createdRefs := map[string]bool{}
+	for _, comment := range created {
+		// Some platforms don't return enough of the created comment to find it again,
+		// e.g. GitLab commit comments, so duplicates can't be detected for them
+		if !refs[comment.Ref()] {
+			log.Ctx(ctx).Debug().Msgf("Not checking for duplicate comments since the created comment %s was not found", comment.Ref())
+			return nil
+		}
+
+		createdRefs[comment.Ref()] = true
+	}
+
+	if len(matchingComments) == len(created) {
+		return nil
+	}
+
+	kept := latestComment(matchingComments)
+	if createdRefs[kept.Ref()] {
+		log.Ctx(ctx).Info().Msg("Another job created a duplicate comment, it will be deleted by that job")
+		return nil
+	}
+
+	log.Ctx(ctx).Warn().Msgf("Another job created comment %s at the same time, deleting the duplicate", color.HiBlueString(kept.Ref()))
+
+	err = h.deleteComments(ctx, result, created)
+	if err != nil {
+		return err
+	}
+
+	return errCommentChanged
+}
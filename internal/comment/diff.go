@@ -0,0 +1,183 @@
+package comment
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a diff.
+const diffContext = 3
+
+// maxDiffCells is the maximum size of the table used for finding the longest common
+// subsequence of lines. If the changed lines would need a larger table, they are shown
+// as all removed and added instead.
+const maxDiffCells = 4000000
+
+// diffOp is a line of a diff. The kind is ' ' for an unchanged line, '-' for a removed
+// line and '+' for an added line.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns the operations for changing the lines in a to the lines in b.
+func diffLines(a []string, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	return ops
+}
+
+// diffMiddle returns the operations for changing the lines in a to the lines in b
+// using the longest common subsequence of the lines.
+func diffMiddle(a []string, b []string) []diffOp {
+	var ops []diffOp
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		default:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		}
+	}
+
+	return ops
+}
+
+// splitDiffLines splits the string into lines for diffing. An empty string has no lines.
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// unifiedDiff returns a unified diff of the lines changed between a and b, or an
+// empty string if they are the same.
+func unifiedDiff(fromName string, toName string, a string, b string) string {
+	ops := diffLines(splitDiffLines(a), splitDiffLines(b))
+
+	// The line numbers in a and b before each operation
+	oldLines := make([]int, len(ops)+1)
+	newLines := make([]int, len(ops)+1)
+
+	var changes []int
+
+	for i, op := range ops {
+		oldLines[i+1] = oldLines[i]
+		newLines[i+1] = newLines[i]
+
+		if op.kind != '+' {
+			oldLines[i+1]++
+		}
+		if op.kind != '-' {
+			newLines[i+1]++
+		}
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	for len(changes) > 0 {
+		// Changes that are close together are shown in the same hunk
+		last := 0
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContext {
+			last++
+		}
+
+		start := changes[0] - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		end := changes[last] + diffContext + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		changes = changes[last+1:]
+
+		oldStart, oldCount := oldLines[start]+1, oldLines[end]-oldLines[start]
+		newStart, newCount := newLines[start]+1, newLines[end]-newLines[start]
+
+		// An empty range starts at the line before it
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,113 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// dryRunComment is a comment that would have been created by a dry run. It
+// implements the Comment interface.
+type dryRunComment struct {
+	body      string
+	createdAt time.Time
+}
+
+// ID always returns an empty string since the comment was not created.
+func (c *dryRunComment) ID() string {
+	return ""
+}
+
+// Body returns the body of the comment
+func (c *dryRunComment) Body() string {
+	return c.body
+}
+
+// Ref returns a placeholder reference since the comment was not created.
+func (c *dryRunComment) Ref() string {
+	return "(dry run)"
+}
+
+// Less compares the comment to another comment by when they were created.
+func (c *dryRunComment) Less(other Comment) bool {
+	return c.createdAt.Before(other.CreatedAt())
+}
+
+// IsHidden always returns false since the comment was just created.
+func (c *dryRunComment) IsHidden() bool {
+	return false
+}
+
+// CreatedAt returns the time the comment was created.
+func (c *dryRunComment) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// UpdatedAt always returns the zero time since the comment was not updated.
+func (c *dryRunComment) UpdatedAt() time.Time {
+	return time.Time{}
+}
+
+// Author always returns an empty string since the comment was not created.
+func (c *dryRunComment) Author() string {
+	return ""
+}
+
+// Version always returns an empty string since the comment was not created.
+func (c *dryRunComment) Version() string {
+	return ""
+}
+
+// Metadata returns the metadata embedded in the comment, or nil if it has none.
+func (c *dryRunComment) Metadata() *Metadata {
+	return parseMetadata(c.body)
+}
+
+// dryRunPlatformHandler wraps a PlatformHandler so comments are still found using the
+// platform's API, but instead of creating, updating, deleting or hiding comments a
+// report of what would be done is written.
+type dryRunPlatformHandler struct {
+	PlatformHandler
+	out io.Writer
+}
+
+// NewDryRunPlatformHandler returns a PlatformHandler that finds comments using the given
+// handler, but only writes a report of any comments that would be created, updated,
+// deleted or hidden to out. Updates are reported as a unified diff of the comment body.
+func NewDryRunPlatformHandler(platformHandler PlatformHandler, out io.Writer) PlatformHandler {
+	return &dryRunPlatformHandler{
+		PlatformHandler: platformHandler,
+		out:             out,
+	}
+}
+
+// CallCreateComment reports the comment that would be created.
+func (h *dryRunPlatformHandler) CallCreateComment(ctx context.Context, body string) (Comment, error) {
+	_, err := fmt.Fprintf(h.out, "Would create a new comment:\n\n%s\n\n", body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dryRunComment{body: body, createdAt: time.Now()}, nil
+}
+
+// CallUpdateComment reports the changes to the comment body that would be made.
+func (h *dryRunPlatformHandler) CallUpdateComment(ctx context.Context, comment Comment, body string) error {
+	diff := unifiedDiff(comment.Ref(), fmt.Sprintf("%s (updated)", comment.Ref()), comment.Body(), body)
+
+	_, err := fmt.Fprintf(h.out, "Would update comment %s:\n\n%s\n", comment.Ref(), diff)
+	return err
+}
+
+// CallDeleteComment reports the comment that would be deleted.
+func (h *dryRunPlatformHandler) CallDeleteComment(ctx context.Context, comment Comment) error {
+	_, err := fmt.Fprintf(h.out, "Would delete comment %s\n\n", comment.Ref())
+	return err
+}
+
+// CallHideComment reports the comment that would be hidden.
+func (h *dryRunPlatformHandler) CallHideComment(ctx context.Context, comment Comment) error {
+	_, err := fmt.Fprintf(h.out, "Would hide comment %s\n\n", comment.Ref())
+	return err
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v41/github"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 )
@@ -21,8 +23,15 @@ type githubComment struct {
 	id          int
 	body        string
 	createdAt   time.Time
+	updatedAt   time.Time
 	url         string
 	isMinimized bool
+	author      string
+}
+
+// ID returns the database ID of the comment.
+func (c *githubComment) ID() string {
+	return strconv.Itoa(c.id)
 }
 
 // Body returns the body of the comment
@@ -57,6 +66,37 @@ func (c *githubComment) IsHidden() bool {
 	return c.isMinimized
 }
 
+// CreatedAt returns the time the comment was created.
+func (c *githubComment) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// UpdatedAt returns the time the comment was last updated.
+func (c *githubComment) UpdatedAt() time.Time {
+	return c.updatedAt
+}
+
+// Author returns the login of the user that wrote the comment.
+func (c *githubComment) Author() string {
+	return c.author
+}
+
+// Version returns the time the comment was last updated, which changes whenever the comment
+// is edited, and the hash of the body. The time only has second precision, so the hash is
+// included to detect edits made within the same second as the comment was read.
+func (c *githubComment) Version() string {
+	if c.updatedAt.IsZero() {
+		return ""
+	}
+
+	return c.updatedAt.UTC().Format(time.RFC3339Nano) + " " + bodyHash(c.body)
+}
+
+// Metadata returns the metadata embedded in the comment, or nil if it has none.
+func (c *githubComment) Metadata() *Metadata {
+	return parseMetadata(c.body)
+}
+
 // GitHubExtra contains any extra inputs that can be passed to the GitHub comment handlers.
 type GitHubExtra struct {
 	// APIURL is the URL of the GitHub API. This can be set to a custom URL if
@@ -119,6 +159,53 @@ func newGitHubAPIClients(ctx context.Context, token string, apiURL string) (*git
 	return v3client, v4client, nil
 }
 
+// githubLogin normalizes the login of a GitHub user. The REST API returns the logins
+// of GitHub Apps with a [bot] suffix but the GraphQL API does not.
+func githubLogin(login string) string {
+	return strings.TrimSuffix(login, "[bot]")
+}
+
+// githubActionsLogin is the login of the bot that comments are posted as when using the
+// GITHUB_TOKEN in GitHub Actions.
+const githubActionsLogin = "github-actions"
+
+// isGitHubInstallationToken returns true if the token is a GitHub App installation token,
+// such as the GITHUB_TOKEN in GitHub Actions.
+func isGitHubInstallationToken(token string) bool {
+	return strings.HasPrefix(token, "ghs_")
+}
+
+// githubAuthenticatedUser calls the GitHub API to get the login of the user the token
+// belongs to. The API rejects this for GitHub App installation tokens. In GitHub Actions
+// the login of the GitHub Actions bot is assumed, since that is who the GITHUB_TOKEN
+// belongs to, but other GitHub Apps' logins can't be known so an error is returned.
+func githubAuthenticatedUser(ctx context.Context, v4client *githubv4.Client, installationToken bool) (string, error) {
+	var q struct {
+		Viewer struct {
+			Login githubv4.String
+		}
+	}
+
+	err := v4client.Query(ctx, &q, nil)
+	if err != nil {
+		if !installationToken {
+			return "", err
+		}
+
+		if os.Getenv("GITHUB_ACTIONS") != "true" {
+			return "", errors.Wrap(err, "Could not get the authenticated user for the GitHub App installation token, set the author to the app's login instead of self")
+		}
+
+		log.Ctx(ctx).Warn().Err(err).Msgf("Could not get the authenticated user for the GitHub App installation token, assuming it is %s. If the token isn't the GITHUB_TOKEN, set the author to the app's login instead of self", githubActionsLogin)
+		return githubActionsLogin, nil
+	}
+
+	return githubLogin(string(q.Viewer.Login)), nil
+}
+
+// githubMaxBodyLength is the maximum number of characters GitHub allows in a comment body.
+const githubMaxBodyLength = 65536
+
 // githubPRHandler is a PlatformHandler for GitHub pull requests. It
 // implements the PlatformHandler interface and contains the functions
 // for finding, creating, updating, deleting comments on GitHub pull requests.
@@ -128,6 +215,9 @@ type githubPRHandler struct {
 	owner    string
 	repo     string
 	prNumber int
+
+	// installationToken is true if the token is a GitHub App installation token.
+	installationToken bool
 }
 
 // newGitHubPRHandler creates a new PlatformHandler for GitHub pull requests.
@@ -158,15 +248,15 @@ func newGitHubPRHandler(ctx context.Context, project string, targetRef string, e
 		owner:    owner,
 		repo:     repo,
 		prNumber: prNumber,
+
+		installationToken: isGitHubInstallationToken(githubExtra.Token),
 	}
 
 	return h, nil
 }
 
-// CallFindMatchingComments calls the GitHub API to find the pull request
-// comments that match the given tag, which has been embedded at the beginning
-// of the comment.
-func (h *githubPRHandler) CallFindMatchingComments(ctx context.Context, tag string) ([]Comment, error) {
+// CallFindComments calls the GitHub API to find all the pull request comments.
+func (h *githubPRHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
 	var q struct {
 		Repository struct {
 			PullRequest struct {
@@ -177,8 +267,12 @@ func (h *githubPRHandler) CallFindMatchingComments(ctx context.Context, tag stri
 						URL         githubv4.String
 						CreatedAt   githubv4.DateTime
 						PublishedAt githubv4.DateTime
+						UpdatedAt   githubv4.DateTime
 						Body        githubv4.String
 						IsMinimized githubv4.Boolean
+						Author      struct {
+							Login githubv4.String
+						}
 					}
 					PageInfo struct {
 						EndCursor   githubv4.String
@@ -213,8 +307,10 @@ func (h *githubPRHandler) CallFindMatchingComments(ctx context.Context, tag stri
 				id:          int(node.DatabaseID),
 				body:        string(node.Body),
 				createdAt:   createdAt.Time,
+				updatedAt:   node.UpdatedAt.Time,
 				url:         string(node.URL),
 				isMinimized: bool(node.IsMinimized),
+				author:      githubLogin(string(node.Author.Login)),
 			})
 		}
 		if !q.Repository.PullRequest.Comments.PageInfo.HasNextPage {
@@ -223,14 +319,7 @@ func (h *githubPRHandler) CallFindMatchingComments(ctx context.Context, tag stri
 		variables["after"] = githubv4.NewString(q.Repository.PullRequest.Comments.PageInfo.EndCursor)
 	}
 
-	var matchingComments []Comment
-	for _, comment := range allComments {
-		if strings.Contains(comment.Body(), tag) {
-			matchingComments = append(matchingComments, comment)
-		}
-	}
-
-	return matchingComments, nil
+	return allComments, nil
 }
 
 // CallCreateComment calls the GitHub API to create a new comment on the pull request.
@@ -251,8 +340,10 @@ func (h *githubPRHandler) CallCreateComment(ctx context.Context, body string) (C
 		id:          int(comment.GetID()),
 		body:        comment.GetBody(),
 		createdAt:   comment.GetCreatedAt(),
+		updatedAt:   comment.GetUpdatedAt(),
 		url:         comment.GetHTMLURL(),
 		isMinimized: false,
+		author:      githubLogin(comment.GetUser().GetLogin()),
 	}, nil
 }
 
@@ -303,6 +394,16 @@ func (h *githubPRHandler) CallHideComment(ctx context.Context, comment Comment)
 	return h.v4client.Mutate(ctx, &m, input, nil)
 }
 
+// CallGetAuthenticatedUser calls the GitHub API to get the login of the user the token belongs to.
+func (h *githubPRHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return githubAuthenticatedUser(ctx, h.v4client, h.installationToken)
+}
+
+// MaxBodyLength returns the maximum number of characters GitHub allows in a comment body.
+func (h *githubPRHandler) MaxBodyLength() int {
+	return githubMaxBodyLength
+}
+
 // githubCommitHandler is a PlatformHandler for GitHub commits. It
 // implements the PlatformHandler interface and contains the functions
 // for finding, creating, updating, deleting comments on GitHub commits.
@@ -312,6 +413,9 @@ type githubCommitHandler struct {
 	owner     string
 	repo      string
 	commitSHA string
+
+	// installationToken is true if the token is a GitHub App installation token.
+	installationToken bool
 }
 
 // newGitHubCommitHandler creates a new PlatformHandler for GitHub commits.
@@ -337,15 +441,15 @@ func newGitHubCommitHandler(ctx context.Context, project string, targetRef strin
 		owner:     owner,
 		repo:      repo,
 		commitSHA: targetRef,
+
+		installationToken: isGitHubInstallationToken(githubExtra.Token),
 	}
 
 	return h, nil
 }
 
-// CallFindMatchingComments calls the GitHub API to find the commit
-// comments that match the given tag, which has been embedded at the beginning
-// of the comment.
-func (h *githubCommitHandler) CallFindMatchingComments(ctx context.Context, tag string) ([]Comment, error) {
+// CallFindComments calls the GitHub API to find all the commit comments.
+func (h *githubCommitHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
 	var q struct {
 		Repository struct {
 			Object struct {
@@ -357,8 +461,12 @@ func (h *githubCommitHandler) CallFindMatchingComments(ctx context.Context, tag
 							URL         githubv4.String
 							CreatedAt   githubv4.DateTime
 							PublishedAt githubv4.DateTime
+							UpdatedAt   githubv4.DateTime
 							Body        githubv4.String
 							IsMinimized githubv4.Boolean
+							Author      struct {
+								Login githubv4.String
+							}
 						}
 						PageInfo struct {
 							EndCursor   githubv4.String
@@ -395,8 +503,10 @@ func (h *githubCommitHandler) CallFindMatchingComments(ctx context.Context, tag
 				id:          int(commentNode.DatabaseID),
 				body:        string(commentNode.Body),
 				createdAt:   createdAt.Time,
+				updatedAt:   commentNode.UpdatedAt.Time,
 				url:         string(commentNode.URL),
 				isMinimized: bool(commentNode.IsMinimized),
+				author:      githubLogin(string(commentNode.Author.Login)),
 			})
 		}
 		if !q.Repository.Object.Commit.Comments.PageInfo.HasNextPage {
@@ -405,14 +515,7 @@ func (h *githubCommitHandler) CallFindMatchingComments(ctx context.Context, tag
 		variables["after"] = githubv4.NewString(q.Repository.Object.Commit.Comments.PageInfo.EndCursor)
 	}
 
-	var matchingComments []Comment
-	for _, comment := range allComments {
-		if strings.Contains(comment.Body(), tag) {
-			matchingComments = append(matchingComments, comment)
-		}
-	}
-
-	return matchingComments, nil
+	return allComments, nil
 }
 
 // CallCreateComment calls the GitHub API to create a new comment on the commit.
@@ -433,8 +536,10 @@ func (h *githubCommitHandler) CallCreateComment(ctx context.Context, body string
 		id:          int(comment.GetID()),
 		body:        comment.GetBody(),
 		createdAt:   comment.GetCreatedAt(),
+		updatedAt:   comment.GetUpdatedAt(),
 		url:         comment.GetHTMLURL(),
 		isMinimized: false,
+		author:      githubLogin(comment.GetUser().GetLogin()),
 	}, nil
 }
 
@@ -487,6 +592,16 @@ func (h *githubCommitHandler) CallHideComment(ctx context.Context, comment Comme
 	return h.v4client.Mutate(ctx, &m, input, nil)
 }
 
+// CallGetAuthenticatedUser calls the GitHub API to get the login of the user the token belongs to.
+func (h *githubCommitHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return githubAuthenticatedUser(ctx, h.v4client, h.installationToken)
+}
+
+// MaxBodyLength returns the maximum number of characters GitHub allows in a comment body.
+func (h *githubCommitHandler) MaxBodyLength() int {
+	return githubMaxBodyLength
+}
+
 func init() {
 	// Here we register the platform handlers against the platform and target type they support
 	registerPlatformHandler("github", "pull-request", newGitHubPRHandler)
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/shurcooL/graphql"
@@ -22,8 +23,16 @@ type gitlabComment struct {
 	id           string
 	body         string
 	createdAt    string
+	updatedAt    string
 	url          string
 	discussionId string
+	author       string
+}
+
+// ID returns the ID of the note. It's empty for commit comments that were just
+// created since GitLab doesn't return their ID.
+func (c *gitlabComment) ID() string {
+	return c.id
 }
 
 // Body returns the body of the comment
@@ -49,10 +58,39 @@ func (c *gitlabComment) Less(other Comment) bool {
 	return c.id < j.id
 }
 
-// IsHidden always returns false for GitLab since GitLab doesn't have a
-// feature for hiding comments.
+// IsHidden returns true if the comment has been hidden. GitLab doesn't have a
+// feature for hiding comments, so they are hidden by collapsing the body.
 func (c *gitlabComment) IsHidden() bool {
-	return false
+	return isHiddenBody(c.body)
+}
+
+// CreatedAt returns the time the comment was created, or the zero time if it can't be parsed.
+func (c *gitlabComment) CreatedAt() time.Time {
+	createdAt, _ := time.Parse(time.RFC3339, c.createdAt)
+	return createdAt
+}
+
+// UpdatedAt returns the time the comment was last updated.
+func (c *gitlabComment) UpdatedAt() time.Time {
+	updatedAt, _ := time.Parse(time.RFC3339, c.updatedAt)
+	return updatedAt
+}
+
+// Author returns the username of the user that wrote the comment.
+func (c *gitlabComment) Author() string {
+	return c.author
+}
+
+// Version returns the time the comment was last updated, which changes whenever the comment
+// is edited, and the hash of the body. The time only has second precision, so the hash is
+// included to detect edits made within the same second as the comment was read.
+func (c *gitlabComment) Version() string {
+	return c.updatedAt + " " + bodyHash(c.body)
+}
+
+// Metadata returns the metadata embedded in the comment, or nil if it has none.
+func (c *gitlabComment) Metadata() *Metadata {
+	return parseMetadata(c.body)
 }
 
 // GitLabExtra contains any extra inputs that can be passed to the GitLab comment handlers.
@@ -84,6 +122,29 @@ func newGitLabAPIClients(ctx context.Context, token string, serverURL string) (*
 	return httpClient, graphql.NewClient(fmt.Sprintf("%sapi/graphql", u.String()), httpClient), nil
 }
 
+// gitlabAuthenticatedUser calls the GitLab API to get the username of the user the token belongs to.
+func gitlabAuthenticatedUser(ctx context.Context, graphqlClient *graphql.Client) (string, error) {
+	var q struct {
+		CurrentUser struct {
+			Username graphql.String
+		}
+	}
+
+	err := graphqlClient.Query(ctx, &q, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if q.CurrentUser.Username == "" {
+		return "", errors.New("Token is not associated with a user")
+	}
+
+	return string(q.CurrentUser.Username), nil
+}
+
+// gitlabMaxBodyLength is the maximum number of characters GitLab allows in a comment body.
+const gitlabMaxBodyLength = 1000000
+
 // gitlabPRHandler is a PlatformHandler for GitLab merge requests. It
 // implements the PlatformHandler interface and contains the functions
 // for finding, creating, updating, deleting comments on GitLab merge requests.
@@ -130,10 +191,8 @@ func newGitLabPRHandler(ctx context.Context, project string, targetRef string, e
 	return h, nil
 }
 
-// CallFindMatchingComments calls the GitLab API to find the merge request
-// comments that match the given tag, which has been embedded at the beginning
-// of the comment.
-func (h *gitlabPRHandler) CallFindMatchingComments(ctx context.Context, tag string) ([]Comment, error) {
+// CallFindComments calls the GitLab API to find all the merge request comments.
+func (h *gitlabPRHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
 	var q struct {
 		Project struct {
 			MergeRequest struct {
@@ -142,7 +201,11 @@ func (h *gitlabPRHandler) CallFindMatchingComments(ctx context.Context, tag stri
 						ID        graphql.String
 						URL       graphql.String
 						CreatedAt graphql.String
+						UpdatedAt graphql.String
 						Body      graphql.String
+						Author    struct {
+							Username graphql.String
+						}
 					}
 					PageInfo struct {
 						EndCursor   graphql.String
@@ -170,7 +233,9 @@ func (h *gitlabPRHandler) CallFindMatchingComments(ctx context.Context, tag stri
 				id:        string(node.ID),
 				body:      string(node.Body),
 				createdAt: string(node.CreatedAt),
+				updatedAt: string(node.UpdatedAt),
 				url:       string(node.URL),
+				author:    string(node.Author.Username),
 			})
 		}
 		if !q.Project.MergeRequest.Notes.PageInfo.HasNextPage {
@@ -179,14 +244,7 @@ func (h *gitlabPRHandler) CallFindMatchingComments(ctx context.Context, tag stri
 		variables["after"] = q.Project.MergeRequest.Notes.PageInfo.EndCursor
 	}
 
-	var matchingComments []Comment
-	for _, comment := range allComments {
-		if strings.Contains(comment.Body(), tag) {
-			matchingComments = append(matchingComments, comment)
-		}
-	}
-
-	return matchingComments, nil
+	return allComments, nil
 }
 
 // CallCreateComment calls the GitLab API to create a new comment on the merge request.
@@ -228,7 +286,11 @@ func (h *gitlabPRHandler) CallCreateComment(ctx context.Context, body string) (C
 	var resData = struct {
 		ID        int    `json:"id"`
 		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
 		Body      string `json:"body"`
+		Author    struct {
+			Username string `json:"username"`
+		} `json:"author"`
 	}{}
 
 	err = json.Unmarshal(resBody, &resData)
@@ -242,7 +304,9 @@ func (h *gitlabPRHandler) CallCreateComment(ctx context.Context, body string) (C
 		id:        string(strconv.Itoa(resData.ID)),
 		body:      resData.Body,
 		createdAt: resData.CreatedAt,
+		updatedAt: resData.UpdatedAt,
 		url:       refURL,
+		author:    resData.Author.Username,
 	}, nil
 }
 
@@ -290,9 +354,20 @@ func (h *gitlabPRHandler) CallDeleteComment(ctx context.Context, comment Comment
 	return h.graphqlClient.Mutate(ctx, &m, variables)
 }
 
-// CallHideComment calls the GitLab API to minimize the merge request comment.
+// CallHideComment calls the GitLab API to hide the merge request comment. GitLab doesn't
+// support minimizing comments, so the body is collapsed into a details block instead.
 func (h *gitlabPRHandler) CallHideComment(ctx context.Context, comment Comment) error {
-	return errors.New("Not implemented")
+	return h.CallUpdateComment(ctx, comment, hideBody(comment.Body()))
+}
+
+// CallGetAuthenticatedUser calls the GitLab API to get the username of the user the token belongs to.
+func (h *gitlabPRHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return gitlabAuthenticatedUser(ctx, h.graphqlClient)
+}
+
+// MaxBodyLength returns the maximum number of characters GitLab allows in a comment body.
+func (h *gitlabPRHandler) MaxBodyLength() int {
+	return gitlabMaxBodyLength
 }
 
 // githubCommitHandler is a PlatformHandler for GitLab commits. It
@@ -336,10 +411,8 @@ func newGitLabCommitHandler(ctx context.Context, project string, targetRef strin
 	return h, nil
 }
 
-// CallFindMatchingComments calls the GitLab API to find the commit
-// comments that match the given tag, which has been embedded at the beginning
-// of the comment.
-func (h *gitlabCommitHandler) CallFindMatchingComments(ctx context.Context, tag string) ([]Comment, error) {
+// CallFindComments calls the GitLab API to find all the commit comments.
+func (h *gitlabCommitHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
 	// Get comments from all pages.
 	var allComments []Comment
 
@@ -382,6 +455,10 @@ func (h *gitlabCommitHandler) CallFindMatchingComments(ctx context.Context, tag
 				ID        int    `json:"id"`
 				Body      string `json:"body"`
 				CreatedAt string `json:"created_at"`
+				UpdatedAt string `json:"updated_at"`
+				Author    struct {
+					Username string `json:"username"`
+				} `json:"author"`
 			} `json:"notes"`
 		}
 
@@ -402,8 +479,10 @@ func (h *gitlabCommitHandler) CallFindMatchingComments(ctx context.Context, tag
 					id:           strconv.Itoa(note.ID),
 					body:         note.Body,
 					createdAt:    note.CreatedAt,
+					updatedAt:    note.UpdatedAt,
 					url:          refURL,
 					discussionId: discussion.ID,
+					author:       note.Author.Username,
 				})
 			}
 		}
@@ -414,14 +493,7 @@ func (h *gitlabCommitHandler) CallFindMatchingComments(ctx context.Context, tag
 		}
 	}
 
-	var matchingComments []Comment
-	for _, comment := range allComments {
-		if strings.Contains(comment.Body(), tag) {
-			matchingComments = append(matchingComments, comment)
-		}
-	}
-
-	return matchingComments, nil
+	return allComments, nil
 }
 
 // CallCreateComment calls the GitLab API to create a new comment on the commit.
@@ -462,6 +534,9 @@ func (h *gitlabCommitHandler) CallCreateComment(ctx context.Context, body string
 	var resData = struct {
 		CreatedAt string `json:"created_at"`
 		Body      string `json:"body"`
+		Author    struct {
+			Username string `json:"username"`
+		} `json:"author"`
 	}{}
 
 	err = json.Unmarshal(resBody, &resData)
@@ -475,6 +550,7 @@ func (h *gitlabCommitHandler) CallCreateComment(ctx context.Context, body string
 		body:      resData.Body,
 		createdAt: resData.CreatedAt,
 		url:       refURL,
+		author:    resData.Author.Username,
 	}, nil
 }
 
@@ -537,9 +613,20 @@ func (h *gitlabCommitHandler) CallDeleteComment(ctx context.Context, comment Com
 	return nil
 }
 
-// CallHideComment calls the GitLab API to minimize the commit comment.
+// CallHideComment calls the GitLab API to hide the commit comment. GitLab doesn't
+// support minimizing comments, so the body is collapsed into a details block instead.
 func (h *gitlabCommitHandler) CallHideComment(ctx context.Context, comment Comment) error {
-	return errors.New("Not implemented")
+	return h.CallUpdateComment(ctx, comment, hideBody(comment.Body()))
+}
+
+// CallGetAuthenticatedUser calls the GitLab API to get the username of the user the token belongs to.
+func (h *gitlabCommitHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return gitlabAuthenticatedUser(ctx, h.graphqlClient)
+}
+
+// MaxBodyLength returns the maximum number of characters GitLab allows in a comment body.
+func (h *gitlabCommitHandler) MaxBodyLength() int {
+	return gitlabMaxBodyLength
 }
 
 func init() {
@@ -4,17 +4,28 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
 var defaultTag = "compost-comment"
 
+// partLabelReserve is the number of characters reserved in each part of a split
+// comment for the part label and the part number in the metadata.
+const partLabelReserve = 100
+
 // Comment is an interface that represents a comment on any platform. It wraps
 // the platform specific comment structures and is used to abstract the
 // logic for finding, creating, updating, and deleting the comments.
 type Comment interface {
+	// ID returns the platform-specific ID of the comment.
+	ID() string
+
 	// Body returns the body of the comment.
 	Body() string
 
@@ -27,16 +38,37 @@ type Comment interface {
 
 	// IsHidden returns true if the comment is hidden or minimized.
 	IsHidden() bool
+
+	// CreatedAt returns the time the comment was created.
+	CreatedAt() time.Time
+
+	// UpdatedAt returns the time the comment was last updated. It returns the zero
+	// time if the platform doesn't return when the comment was updated.
+	UpdatedAt() time.Time
+
+	// Author returns the login of the user that wrote the comment. It returns an
+	// empty string if the platform handler doesn't support comment authors.
+	Author() string
+
+	// Version returns a value that changes whenever the comment is edited, e.g. the time
+	// it was last updated. It's used to detect if a comment was changed by another job
+	// between reading and updating it. It returns an empty string if the platform handler
+	// doesn't support comment versions.
+	Version() string
+
+	// Metadata returns the hidden metadata embedded in the comment, or nil if
+	// the comment has no metadata, e.g. if it was posted by an older version.
+	Metadata() *Metadata
 }
 
 // PlatformHandler is an interface that represents a platform specific handler.
 // It is used to call the platform-specific APIs for finding, creating, updating
 // and deleting comments.
 type PlatformHandler interface {
-	// CallFindMatchingComments calls the platform-specific API to find
-	// comments that match the given tag, which has been embedded at the beginning
-	// of the comment.
-	CallFindMatchingComments(ctx context.Context, tag string) ([]Comment, error)
+	// CallFindComments calls the platform-specific API to find all the comments
+	// on the pull request or commit. The comments are matched against the tag by
+	// the CommentHandler.
+	CallFindComments(ctx context.Context) ([]Comment, error)
 
 	// CallCreateComment calls the platform-specific API to create a new comment.
 	CallCreateComment(ctx context.Context, body string) (Comment, error)
@@ -51,6 +83,15 @@ type PlatformHandler interface {
 	// This functionality is not supported by all platforms, in which case this
 	// will throw a NotImplemented error.
 	CallHideComment(ctx context.Context, comment Comment) error
+
+	// CallGetAuthenticatedUser calls the platform-specific API to get the login of the
+	// user the token belongs to. This functionality is not supported by all platforms,
+	// in which case this will throw a NotImplemented error.
+	CallGetAuthenticatedUser(ctx context.Context) (string, error)
+
+	// MaxBodyLength returns the maximum number of characters the platform
+	// allows in a comment body.
+	MaxBodyLength() int
 }
 
 // PlatformHandlerFactory is a function that creates a new PlatformHandler.
@@ -104,6 +145,37 @@ func NewPlatformHandlerFactory(ctx context.Context, platform string, targetType
 type CommentHandler struct {
 	PlatformHandler PlatformHandler
 	Tag             string
+
+	// Metadata is embedded in the comments that are posted. The tag and body hash
+	// are set automatically.
+	Metadata Metadata
+
+	// Author restricts the matching comments to the ones written by the user with
+	// this login. If set to "self" the login of the authenticated user is used.
+	Author string
+
+	// HistoryLimit is the maximum number of previous versions of the body that
+	// UpdateCommentWithHistory keeps in the comment.
+	HistoryLimit int
+
+	// Target describes where the comments are posted.
+	Target Target
+
+	// Truncate makes bodies that are too long for a single comment be truncated instead
+	// of split into multiple parts if it is set.
+	Truncate *TruncateOptions
+
+	// MaxCommentLength overrides the maximum number of characters the platform allows
+	// in a comment if it is set.
+	MaxCommentLength int
+}
+
+// Target is the platform, project and pull request or commit that comments are posted to.
+type Target struct {
+	Platform   string
+	Project    string
+	TargetType string
+	TargetRef  string
 }
 
 // NewCommentHandler creates a new CommentHandler.
@@ -118,15 +190,36 @@ func NewCommentHandler(ctx context.Context, platformHandler PlatformHandler, tag
 	}, nil
 }
 
-// matchingComments returns all comments that match the tag.
+// matchingComments returns all comments that match the tag. A comment only matches
+// if its first line is the markdown tag marker for exactly this tag. If the author
+// is set, only comments written by the author match.
 func (h *CommentHandler) matchingComments(ctx context.Context) ([]Comment, error) {
 	log.Ctx(ctx).Info().Msgf("Finding matching comments for tag %s", h.Tag)
 
-	matchingComments, err := h.PlatformHandler.CallFindMatchingComments(ctx, h.Tag)
+	comments, err := h.PlatformHandler.CallFindComments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	author, err := h.authorLogin(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	matchingComments := []Comment{}
+	for _, comment := range comments {
+		if !hasMarkdownTag(comment.Body(), h.Tag) {
+			continue
+		}
+
+		if author != "" && !strings.EqualFold(comment.Author(), author) {
+			log.Ctx(ctx).Debug().Msgf("Ignoring comment %s by %s", comment.Ref(), comment.Author())
+			continue
+		}
+
+		matchingComments = append(matchingComments, comment)
+	}
+
 	if len(matchingComments) == 1 {
 		log.Ctx(ctx).Info().Msg("Found 1 matching comment")
 	} else {
@@ -136,6 +229,23 @@ func (h *CommentHandler) matchingComments(ctx context.Context) ([]Comment, error
 	return matchingComments, nil
 }
 
+// authorLogin returns the login of the author to filter the matching comments by,
+// or an empty string if the comments are not filtered by author.
+func (h *CommentHandler) authorLogin(ctx context.Context) (string, error) {
+	if h.Author != "self" {
+		return h.Author, nil
+	}
+
+	login, err := h.PlatformHandler.CallGetAuthenticatedUser(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "Error getting authenticated user")
+	}
+
+	log.Ctx(ctx).Info().Msgf("Only matching comments by %s", login)
+
+	return login, nil
+}
+
 // LatestMatchingComment returns the latest matching comment.
 func (h *CommentHandler) LatestMatchingComment(ctx context.Context) (Comment, error) {
 	matchingComments, err := h.matchingComments(ctx)
@@ -143,39 +253,326 @@ func (h *CommentHandler) LatestMatchingComment(ctx context.Context) (Comment, er
 		return nil, err
 	}
 
+	return latestComment(matchingComments), nil
+}
+
+// MatchingComments returns all the matching comments, ordered by Less.
+func (h *CommentHandler) MatchingComments(ctx context.Context) ([]Comment, error) {
+	matchingComments, err := h.matchingComments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	sort.Slice(matchingComments, func(i, j int) bool {
 		return matchingComments[i].Less(matchingComments[j])
 	})
 
-	if len(matchingComments) == 0 {
+	return matchingComments, nil
+}
+
+// latestComment returns the latest of the given comments, or nil if there are none.
+func latestComment(comments []Comment) Comment {
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].Less(comments[j])
+	})
+
+	if len(comments) == 0 {
+		return nil
+	}
+
+	return comments[0]
+}
+
+// latestMatchingParts returns the parts of the latest matching comment, ordered by
+// part number. A comment that wasn't split into multiple parts has a single part.
+// The parts of a comment are the matching comments with the same body hash.
+func (h *CommentHandler) latestMatchingParts(ctx context.Context) ([]Comment, error) {
+	matchingComments, err := h.matchingComments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := latestComment(matchingComments)
+	if latest == nil {
 		return nil, nil
 	}
 
-	return matchingComments[0], nil
+	metadata := latest.Metadata()
+	if metadata == nil || metadata.Parts <= 1 {
+		return []Comment{latest}, nil
+	}
+
+	partsByNumber := make([]Comment, metadata.Parts)
+
+	for _, comment := range matchingComments {
+		m := comment.Metadata()
+		if m == nil || m.BodyHash != metadata.BodyHash || m.Parts != metadata.Parts || m.Part < 1 || m.Part > m.Parts {
+			continue
+		}
+
+		if partsByNumber[m.Part-1] == nil {
+			partsByNumber[m.Part-1] = comment
+		}
+	}
+
+	parts := []Comment{}
+	for _, comment := range partsByNumber {
+		if comment != nil {
+			parts = append(parts, comment)
+		}
+	}
+
+	return parts, nil
+}
+
+// samePart returns true if the metadata is for the same part of the same body. The
+// parts of a split comment are found by their body hash and number of parts, so an
+// unchanged part must still be updated if the other parts have changed.
+func samePart(a *Metadata, b *Metadata) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.BodyHash == b.BodyHash && a.Part == b.Part && a.Parts == b.Parts
+}
+
+// metadata returns the metadata to embed in a comment with the given body.
+func (h *CommentHandler) metadata(body string) Metadata {
+	metadata := h.Metadata
+	metadata.Tag = h.Tag
+	metadata.PostedAt = time.Now().UTC().Format(time.RFC3339)
+
+	// Only the current content is hashed, not the previous versions
+	current, _ := splitHistory(body)
+	metadata.BodyHash = bodyHash(current)
+
+	return metadata
+}
+
+// bodyWithTag returns the body with the tag and metadata embedded at the beginning.
+func (h *CommentHandler) bodyWithTag(body string) (string, error) {
+	return addTagAndMetadata(body, h.Tag, h.metadata(body))
 }
 
-// UpdateComment updates the comment with the given body.
-func (h *CommentHandler) UpdateComment(ctx context.Context, body string) error {
-	bodyWithTag := addMarkdownTag(body, h.Tag)
+// MaxBodyLength returns the maximum length of a body that can be posted in a single
+// comment, which is the platform's maximum length minus the space needed for the tag
+// and metadata.
+func (h *CommentHandler) MaxBodyLength() (int, error) {
+	bodyWithTag, err := h.bodyWithTag("")
+	if err != nil {
+		return 0, err
+	}
+
+	return h.maxCommentLength() - utf8.RuneCountInString(bodyWithTag), nil
+}
+
+// maxCommentLength returns the maximum number of characters allowed in a comment,
+// including the tag and metadata.
+func (h *CommentHandler) maxCommentLength() int {
+	if h.MaxCommentLength > 0 {
+		return h.MaxCommentLength
+	}
+
+	return h.PlatformHandler.MaxBodyLength()
+}
+
+// truncateLength returns the maximum length of the truncate options, or the maximum
+// length the platform allows if that is lower.
+func (h *CommentHandler) truncateLength() (int, error) {
+	maxLength, err := h.MaxBodyLength()
+	if err != nil {
+		return 0, err
+	}
+
+	if h.Truncate.MaxLength > 0 && h.Truncate.MaxLength < maxLength {
+		maxLength = h.Truncate.MaxLength
+	}
+
+	return maxLength, nil
+}
 
-	latestMatchingComment, err := h.LatestMatchingComment(ctx)
+// truncateBody truncates the body to the maximum length of the truncate options, or
+// the maximum length the platform allows if that is lower.
+func (h *CommentHandler) truncateBody(body string) (string, error) {
+	maxLength, err := h.truncateLength()
+	if err != nil {
+		return "", err
+	}
+
+	return TruncateBody(body, maxLength, h.Truncate.Footer), nil
+}
+
+// bodyPartsWithTag returns the bodies of the comments to post for the given body, with
+// the tag and metadata embedded at the beginning of each. If the body is longer than the
+// platform allows it is truncated if Truncate is set, otherwise it is split into multiple
+// parts, which have the part number in their metadata and share the hash of the full body.
+func (h *CommentHandler) bodyPartsWithTag(body string) ([]string, error) {
+	if h.Truncate != nil {
+		var err error
+		body, err = h.truncateBody(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bodyWithTag, err := h.bodyWithTag(body)
+	if err != nil {
+		return nil, err
+	}
+
+	maxLength := h.maxCommentLength()
+	if utf8.RuneCountInString(bodyWithTag) <= maxLength {
+		return []string{bodyWithTag}, nil
+	}
+
+	// Leave space for the tag, metadata and part label in each part
+	reserved := utf8.RuneCountInString(bodyWithTag) - utf8.RuneCountInString(body) + partLabelReserve
+	parts := splitBody(body, maxLength-reserved)
+
+	metadata := h.metadata(body)
+	metadata.Parts = len(parts)
+
+	bodies := make([]string, 0, len(parts))
+
+	for i, part := range parts {
+		metadata.Part = i + 1
+
+		partWithTag, err := addTagAndMetadata(part+partLabel(i+1, len(parts)), h.Tag, metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		bodies = append(bodies, partWithTag)
+	}
+
+	return bodies, nil
+}
+
+// UpdateComment updates the comment with the given body. If the body has to be
+// split into multiple parts, the parts of the latest comment are updated, any
+// extra parts are created and any parts that are no longer needed are deleted.
+// If the comment is changed by another job while it's being updated, the update
+// is retried with the latest comment.
+func (h *CommentHandler) UpdateComment(ctx context.Context, body string) (*Result, error) {
+	return retryOnConflict(ctx, func(result *Result) error {
+		latestParts, err := h.latestMatchingParts(ctx)
+		if err != nil {
+			return err
+		}
+
+		return h.updateComment(ctx, result, latestParts, body)
+	})
+}
+
+// updateComment updates the given parts of the latest comment with the given body.
+// It returns errCommentChanged if the comment was changed since the parts were read,
+// or if another job created a matching comment at the same time as this one.
+// The actions taken are added to the result.
+func (h *CommentHandler) updateComment(ctx context.Context, result *Result, latestParts []Comment, body string) error {
+	bodies, err := h.bodyPartsWithTag(body)
 	if err != nil {
 		return err
 	}
 
-	if latestMatchingComment != nil {
-		if latestMatchingComment.Body() == bodyWithTag {
-			log.Ctx(ctx).Info().Msgf("Not updating comment since the latest one matches exactly: %s", color.HiBlueString(latestMatchingComment.Ref()))
+	if len(bodies) > 1 {
+		log.Ctx(ctx).Info().Msgf("Comment is too long so it will be split into %d parts", len(bodies))
+	}
+
+	// The comment is only checked for changes once, before it is first modified
+	checked := false
+	checkUnchanged := func() error {
+		if checked {
 			return nil
 		}
+		checked = true
+
+		return h.checkUnchanged(ctx, latestParts)
+	}
+
+	var created []Comment
+
+	for i, bodyWithTag := range bodies {
+		if i >= len(latestParts) {
+			log.Ctx(ctx).Info().Msg("Creating new comment")
+
+			comment, err := h.PlatformHandler.CallCreateComment(ctx, bodyWithTag)
+			if err != nil {
+				return err
+			}
+
+			log.Ctx(ctx).Info().Msgf("Created new comment %s", color.HiBlueString(comment.Ref()))
+			result.add(ActionCreated, comment)
+			created = append(created, comment)
+			continue
+		}
+
+		latestMatchingComment := latestParts[i]
+
+		// The metadata is ignored when comparing since it changes on every run, apart from
+		// the fields that group the parts of a split comment
+		if stripMetadata(latestMatchingComment.Body()) == stripMetadata(bodyWithTag) && samePart(latestMatchingComment.Metadata(), parseMetadata(bodyWithTag)) {
+			log.Ctx(ctx).Info().Msgf("Not updating comment since the latest one matches exactly: %s", color.HiBlueString(latestMatchingComment.Ref()))
+			result.add(ActionUnchanged, latestMatchingComment)
+			continue
+		}
+
+		err := checkUnchanged()
+		if err != nil {
+			return err
+		}
 
 		log.Ctx(ctx).Info().Msgf("Updating comment %s", color.HiBlueString(latestMatchingComment.Ref()))
 
-		err := h.PlatformHandler.CallUpdateComment(ctx, latestMatchingComment, bodyWithTag)
+		err = h.PlatformHandler.CallUpdateComment(ctx, latestMatchingComment, bodyWithTag)
 		if err != nil {
 			return err
 		}
-	} else {
+
+		result.add(ActionUpdated, latestMatchingComment)
+	}
+
+	if len(latestParts) > len(bodies) {
+		err := checkUnchanged()
+		if err != nil {
+			return err
+		}
+
+		return h.deleteComments(ctx, result, latestParts[len(bodies):])
+	}
+
+	// Another job may have created a matching comment at the same time
+	if len(latestParts) == 0 && len(created) > 0 {
+		return h.removeDuplicates(ctx, result, created)
+	}
+
+	return nil
+}
+
+// NewComment creates a new comment with the given body. If the body has to be
+// split into multiple parts, a comment is created for each part.
+func (h *CommentHandler) NewComment(ctx context.Context, body string) (*Result, error) {
+	result := newResult()
+
+	err := h.newComment(ctx, result, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// newComment creates a new comment with the given body and adds the actions taken to the result.
+func (h *CommentHandler) newComment(ctx context.Context, result *Result, body string) error {
+	bodies, err := h.bodyPartsWithTag(body)
+	if err != nil {
+		return err
+	}
+
+	if len(bodies) > 1 {
+		log.Ctx(ctx).Info().Msgf("Comment is too long so it will be split into %d parts", len(bodies))
+	}
+
+	for _, bodyWithTag := range bodies {
 		log.Ctx(ctx).Info().Msg("Creating new comment")
 
 		comment, err := h.PlatformHandler.CallCreateComment(ctx, bodyWithTag)
@@ -183,45 +580,160 @@ func (h *CommentHandler) UpdateComment(ctx context.Context, body string) error {
 			return err
 		}
 
-		log.Ctx(ctx).Info().Msgf("Created new comment %s", color.HiBlueString(comment.Ref()))
+		log.Ctx(ctx).Info().Msgf("Created new comment: %s", color.HiBlueString(comment.Ref()))
+		result.add(ActionCreated, comment)
 	}
 
 	return nil
 }
 
-// NewComment creates a new comment with the given body.
-func (h *CommentHandler) NewComment(ctx context.Context, body string) error {
-	bodyWithTag := body
+// CleanupOptions are the options for selecting which matching comments are
+// deleted or hidden by DeleteComments and HideComments.
+type CleanupOptions struct {
+	// KeepLatest is the number of most recently created comments to keep. The parts
+	// of a split comment are counted as one comment.
+	KeepLatest int
+
+	// OlderThan only selects comments that were created longer ago than this.
+	// If not set, comments are selected regardless of when they were created.
+	// Comments are kept if when they were created isn't known.
+	OlderThan time.Duration
+}
+
+// commentGroups groups the comments into the parts of each comment, ordered by Less.
+// The parts of a split comment share the body hash and number of parts, and a comment
+// that wasn't split is in a group by itself.
+func commentGroups(comments []Comment) [][]Comment {
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].Less(comments[j])
+	})
+
+	var groups [][]Comment
+	groupIndex := map[string]int{}
+
+	for _, comment := range comments {
+		m := comment.Metadata()
+		if m == nil || m.Parts <= 1 {
+			groups = append(groups, []Comment{comment})
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%d", m.BodyHash, m.Parts)
+
+		if i, ok := groupIndex[key]; ok {
+			groups[i] = append(groups[i], comment)
+			continue
+		}
+
+		groupIndex[key] = len(groups)
+		groups = append(groups, []Comment{comment})
+	}
+
+	return groups
+}
+
+// isOlderThan returns true if all the comments were created longer ago than the given
+// duration. Comments are never older if when they were created isn't known.
+func isOlderThan(comments []Comment, d time.Duration) bool {
+	for _, comment := range comments {
+		createdAt := comment.CreatedAt()
+		if createdAt.IsZero() || time.Since(createdAt) <= d {
+			return false
+		}
+	}
+
+	return true
+}
+
+// selectComments returns the comments that should be deleted or hidden for the given options.
+// All the parts of a split comment are selected together, so they are counted as one comment.
+func selectComments(comments []Comment, opts CleanupOptions) []Comment {
+	groups := commentGroups(comments)
+
+	keep := opts.KeepLatest
+	if keep > len(groups) {
+		keep = len(groups)
+	}
+
+	selected := []Comment{}
+	for _, group := range groups[:len(groups)-keep] {
+		if opts.OlderThan > 0 && !isOlderThan(group, opts.OlderThan) {
+			continue
+		}
+
+		selected = append(selected, group...)
+	}
 
-	log.Ctx(ctx).Info().Msg("Creating new comment")
+	return selected
+}
 
-	comment, err := h.PlatformHandler.CallCreateComment(ctx, bodyWithTag)
+// DeleteComments deletes the matching comments, except for the KeepLatest most recent
+// ones. If OlderThan is set, only comments created longer ago than that are deleted.
+func (h *CommentHandler) DeleteComments(ctx context.Context, opts CleanupOptions) (*Result, error) {
+	matchingComments, err := h.matchingComments(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	log.Ctx(ctx).Info().Msgf("Created new comment: %s", color.HiBlueString(comment.Ref()))
+	result := newResult()
+
+	err = h.deleteComments(ctx, result, selectComments(matchingComments, opts))
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	return result, nil
+}
+
+// HideComments hides the matching comments, except for the KeepLatest most recent visible
+// ones. If OlderThan is set, only comments created longer ago than that are hidden.
+func (h *CommentHandler) HideComments(ctx context.Context, opts CleanupOptions) (*Result, error) {
+	matchingComments, err := h.matchingComments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	visibleComments := []Comment{}
+	for _, comment := range matchingComments {
+		if !comment.IsHidden() {
+			visibleComments = append(visibleComments, comment)
+		}
+	}
+
+	result := newResult()
+
+	err = h.hideComments(ctx, result, selectComments(visibleComments, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
 // HideAndNewComment hides/minimizes all existing matching comment and creates a new one with the given body.
-func (h *CommentHandler) HideAndNewComment(ctx context.Context, body string) error {
+func (h *CommentHandler) HideAndNewComment(ctx context.Context, body string) (*Result, error) {
 	matchingComments, err := h.matchingComments(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = h.hideComments(ctx, matchingComments)
+	result := newResult()
+
+	err = h.hideComments(ctx, result, matchingComments)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return h.NewComment(ctx, body)
+	err = h.newComment(ctx, result, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// hideComments hides/minimizes all the given comments.
-func (h *CommentHandler) hideComments(ctx context.Context, comments []Comment) error {
+// hideComments hides/minimizes all the given comments and adds the actions taken to the result.
+func (h *CommentHandler) hideComments(ctx context.Context, result *Result, comments []Comment) error {
 	visibleComments := []Comment{}
 
 	for _, comment := range comments {
@@ -250,28 +762,37 @@ func (h *CommentHandler) hideComments(ctx context.Context, comments []Comment) e
 		if err != nil {
 			return err
 		}
+
+		result.add(ActionHidden, comment)
 	}
 
 	return nil
 }
 
 // DeleteAndNewComment deletes all existing matching comment and creates a new one with the given body.
-func (h *CommentHandler) DeleteAndNewComment(ctx context.Context, body string) error {
+func (h *CommentHandler) DeleteAndNewComment(ctx context.Context, body string) (*Result, error) {
 	matchingComments, err := h.matchingComments(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = h.deleteComments(ctx, matchingComments)
+	result := newResult()
+
+	err = h.deleteComments(ctx, result, matchingComments)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return h.NewComment(ctx, body)
+	err = h.newComment(ctx, result, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// deleteComments hides/minimizes all the given comments.
-func (h *CommentHandler) deleteComments(ctx context.Context, comments []Comment) error {
+// deleteComments deletes all the given comments and adds the actions taken to the result.
+func (h *CommentHandler) deleteComments(ctx context.Context, result *Result, comments []Comment) error {
 	if len(comments) == 1 {
 		log.Ctx(ctx).Info().Msg("Deleting 1 comment")
 	} else {
@@ -284,6 +805,8 @@ func (h *CommentHandler) deleteComments(ctx context.Context, comments []Comment)
 		if err != nil {
 			return err
 		}
+
+		result.add(ActionDeleted, comment)
 	}
 
 	return nil
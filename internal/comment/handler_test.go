@@ -0,0 +1,105 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeComment is a comment stored by fakePlatformHandler.
+type fakeComment struct {
+	id      int
+	body    string
+	version int
+}
+
+func (c *fakeComment) ID() string           { return fmt.Sprint(c.id) }
+func (c *fakeComment) Body() string         { return c.body }
+func (c *fakeComment) Ref() string          { return fmt.Sprintf("comment-%d", c.id) }
+func (c *fakeComment) Less(o Comment) bool  { return c.id < o.(*fakeComment).id }
+func (c *fakeComment) IsHidden() bool       { return false }
+func (c *fakeComment) CreatedAt() time.Time { return time.Time{} }
+func (c *fakeComment) UpdatedAt() time.Time { return time.Time{} }
+func (c *fakeComment) Author() string       { return "" }
+func (c *fakeComment) Version() string      { return fmt.Sprint(c.version) }
+func (c *fakeComment) Metadata() *Metadata  { return parseMetadata(c.body) }
+
+// fakePlatformHandler is a PlatformHandler that keeps the comments in memory.
+type fakePlatformHandler struct {
+	comments      []*fakeComment
+	nextID        int
+	maxBodyLength int
+}
+
+func (h *fakePlatformHandler) CallFindComments(ctx context.Context) ([]Comment, error) {
+	comments := make([]Comment, 0, len(h.comments))
+	for _, c := range h.comments {
+		copy := *c
+		comments = append(comments, &copy)
+	}
+
+	return comments, nil
+}
+
+func (h *fakePlatformHandler) CallCreateComment(ctx context.Context, body string) (Comment, error) {
+	h.nextID++
+	c := &fakeComment{id: h.nextID, body: body}
+	h.comments = append(h.comments, c)
+
+	copy := *c
+	return &copy, nil
+}
+
+func (h *fakePlatformHandler) CallUpdateComment(ctx context.Context, comment Comment, body string) error {
+	c := h.find(comment)
+	if c == nil {
+		return fmt.Errorf("comment %s not found", comment.ID())
+	}
+
+	c.body = body
+	c.version++
+
+	return nil
+}
+
+func (h *fakePlatformHandler) CallDeleteComment(ctx context.Context, comment Comment) error {
+	for i, c := range h.comments {
+		if c.ID() == comment.ID() {
+			h.comments = append(h.comments[:i], h.comments[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("comment %s not found", comment.ID())
+}
+
+func (h *fakePlatformHandler) CallHideComment(ctx context.Context, comment Comment) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (h *fakePlatformHandler) CallGetAuthenticatedUser(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
+
+func (h *fakePlatformHandler) MaxBodyLength() int {
+	return h.maxBodyLength
+}
+
+// find returns the stored comment with the same ID as the given comment.
+func (h *fakePlatformHandler) find(comment Comment) *fakeComment {
+	for _, c := range h.comments {
+		if c.ID() == comment.ID() {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// testContext returns a context with a logger that discards the logs.
+func testContext() context.Context {
+	logger := zerolog.Nop()
+	return logger.WithContext(context.Background())
+}
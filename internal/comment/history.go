@@ -0,0 +1,216 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+)
+
+// historyMarker is the markdown comment that separates the current content of a
+// comment from the collapsed previous versions.
+const historyMarker = "compost-history"
+
+// historyEntryMarker is the markdown comment at the beginning of each previous version.
+const historyEntryMarker = "compost-history-entry"
+
+// defaultHistoryLimit is the number of previous versions kept if HistoryLimit is not set.
+const defaultHistoryLimit = 5
+
+// splitHistory splits the content of a comment into the current content and the
+// previous versions, most recent first. The markers are not included.
+func splitHistory(s string) (string, []string) {
+	historyTag := markdownTag(historyMarker)
+	entryTag := markdownTag(historyEntryMarker)
+
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+		if strings.TrimRight(line, " \t\r") != historyTag {
+			continue
+		}
+
+		current := strings.TrimSpace(strings.Join(lines[:i], "\n"))
+
+		var entries []string
+		var entry []string
+
+		for _, line := range lines[i+1:] {
+			if strings.TrimRight(line, " \t\r") == entryTag {
+				if len(entry) > 0 {
+					entries = append(entries, strings.TrimSpace(strings.Join(entry, "\n")))
+				}
+				entry = []string{}
+				continue
+			}
+
+			if entry != nil {
+				entry = append(entry, line)
+			}
+		}
+
+		if len(entry) > 0 {
+			entries = append(entries, strings.TrimSpace(strings.Join(entry, "\n")))
+		}
+
+		return current, entries
+	}
+
+	return strings.TrimSpace(s), nil
+}
+
+// joinHistory appends the previous versions to the current content of a comment.
+func joinHistory(current string, entries []string) string {
+	if len(entries) == 0 {
+		return current
+	}
+
+	parts := []string{current, markdownTag(historyMarker)}
+	for _, entry := range entries {
+		parts = append(parts, fmt.Sprintf("%s\n%s", markdownTag(historyEntryMarker), entry))
+	}
+
+	// Markdown comments must be separated from the content by a blank line
+	return strings.Join(parts, "\n\n")
+}
+
+// historyEntry returns a collapsed previous version of a comment. The summary
+// includes when the version was posted and the commit SHA it was posted for,
+// if they are known from the comment metadata.
+func historyEntry(content string, metadata *Metadata) string {
+	summary := "Previous version"
+
+	if metadata != nil {
+		if metadata.PostedAt != "" {
+			summary += fmt.Sprintf(" from %s", metadata.PostedAt)
+		}
+
+		if metadata.CommitSHA != "" {
+			sha := metadata.CommitSHA
+			if len(sha) > 7 {
+				sha = sha[:7]
+			}
+			summary += fmt.Sprintf(" (commit %s)", sha)
+		}
+	}
+
+	// Any code fence or details block left open in the content would swallow the closing tag
+	return fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n\n</details>", summary, closeMarkdown(content))
+}
+
+// UpdateCommentWithHistory updates the comment with the given body, keeping the previous
+// versions of the body collapsed below it. Up to HistoryLimit previous versions are kept,
+// and the oldest ones are removed if the body would exceed the platform's maximum length.
+// If Truncate is set the body is truncated first, and previous versions are removed
+// until the full comment fits the truncate options' maximum length. If the body is too
+// long even without any previous versions it is split into multiple parts by
+// UpdateComment instead. If the comment is changed by another job while it's
+// being updated, the update is retried with the latest comment.
+func (h *CommentHandler) UpdateCommentWithHistory(ctx context.Context, body string) (*Result, error) {
+	return retryOnConflict(ctx, func(result *Result) error {
+		return h.updateCommentWithHistory(ctx, result, body)
+	})
+}
+
+// updateCommentWithHistory updates the latest comment with the given body, keeping the
+// previous versions, and adds the actions taken to the result. It returns errCommentChanged
+// if the comment was changed by another job.
+func (h *CommentHandler) updateCommentWithHistory(ctx context.Context, result *Result, body string) error {
+	maxLength := h.maxCommentLength()
+
+	// The body is truncated before it's compared with the previous version, which
+	// was also truncated when it was posted
+	var truncateLength int
+	if h.Truncate != nil {
+		var err error
+		truncateLength, err = h.truncateLength()
+		if err != nil {
+			return err
+		}
+
+		body = TruncateBody(body, truncateLength, h.Truncate.Footer)
+	}
+
+	latestParts, err := h.latestMatchingParts(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(latestParts) == 0 {
+		return h.updateComment(ctx, result, latestParts, body)
+	}
+
+	latestMatchingComment := latestParts[0]
+
+	// Split comments don't keep any history since the body is already too long
+	if metadata := latestMatchingComment.Metadata(); metadata != nil && metadata.Parts > 1 {
+		return h.updateComment(ctx, result, latestParts, body)
+	}
+
+	_, latestContent := splitHeader(latestMatchingComment.Body())
+	previous, entries := splitHistory(latestContent)
+
+	if previous == strings.TrimSpace(body) {
+		log.Ctx(ctx).Info().Msgf("Not updating comment since the latest one matches exactly: %s", color.HiBlueString(latestMatchingComment.Ref()))
+		result.add(ActionUnchanged, latestMatchingComment)
+		return nil
+	}
+
+	entries = append([]string{historyEntry(previous, latestMatchingComment.Metadata())}, entries...)
+
+	limit := h.HistoryLimit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	var bodyWithTag string
+
+	for {
+		bodyWithHistory := joinHistory(body, entries)
+
+		bodyWithTag, err = h.bodyWithTag(bodyWithHistory)
+		if err != nil {
+			return err
+		}
+
+		fits := utf8.RuneCountInString(bodyWithTag) <= maxLength
+		if h.Truncate != nil && utf8.RuneCountInString(bodyWithHistory) > truncateLength {
+			fits = false
+		}
+
+		if fits {
+			break
+		}
+
+		// If the body is too long without any history it has to be split
+		if len(entries) == 0 {
+			return h.updateComment(ctx, result, latestParts, body)
+		}
+
+		log.Ctx(ctx).Debug().Msg("Removing the oldest previous version to fit the maximum comment length")
+		entries = entries[:len(entries)-1]
+	}
+
+	err = h.checkUnchanged(ctx, latestParts)
+	if err != nil {
+		return err
+	}
+
+	log.Ctx(ctx).Info().Msgf("Updating comment %s with %d previous versions", color.HiBlueString(latestMatchingComment.Ref()), len(entries))
+
+	err = h.PlatformHandler.CallUpdateComment(ctx, latestMatchingComment, bodyWithTag)
+	if err != nil {
+		return err
+	}
+
+	result.add(ActionUpdated, latestMatchingComment)
+
+	return nil
+}
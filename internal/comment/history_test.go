@@ -0,0 +1,88 @@
+package comment
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestUpdateCommentWithHistoryTruncate(t *testing.T) {
+	ctx := testContext()
+	platform := &fakePlatformHandler{maxBodyLength: 65536}
+	h := &CommentHandler{
+		PlatformHandler: platform,
+		Tag:             "test",
+		Truncate:        &TruncateOptions{MaxLength: 300, Footer: "cut"},
+	}
+
+	first := "first\n\n" + strings.Repeat("line 1\n", 100)
+	second := "second\n\n" + strings.Repeat("line 2\n", 100)
+	short := "short"
+
+	steps := []struct {
+		name        string
+		body        string
+		wantAction  Action
+		wantContent string
+	}{
+		{
+			name:        "first post is truncated",
+			body:        first,
+			wantAction:  ActionCreated,
+			wantContent: TruncateBody(first, 300, "cut"),
+		},
+		{
+			name:        "same body is unchanged",
+			body:        first,
+			wantAction:  ActionUnchanged,
+			wantContent: TruncateBody(first, 300, "cut"),
+		},
+		{
+			name:        "updated body is truncated without history that doesn't fit",
+			body:        second,
+			wantAction:  ActionUpdated,
+			wantContent: TruncateBody(second, 300, "cut"),
+		},
+		{
+			name:        "history is removed to fit the maximum length",
+			body:        short,
+			wantAction:  ActionUpdated,
+			wantContent: short,
+		},
+		{
+			name:       "history that fits is kept",
+			body:       "shorter",
+			wantAction: ActionUpdated,
+		},
+	}
+
+	for _, step := range steps {
+		result, err := h.UpdateCommentWithHistory(ctx, step.body)
+		if err != nil {
+			t.Fatalf("%s: UpdateCommentWithHistory() error = %v", step.name, err)
+		}
+
+		if result.Action != step.wantAction {
+			t.Errorf("%s: action = %s, want %s", step.name, result.Action, step.wantAction)
+		}
+
+		if len(platform.comments) != 1 {
+			t.Fatalf("%s: got %d comments, want 1", step.name, len(platform.comments))
+		}
+
+		content := strings.TrimSpace(BodyContent(platform.comments[0]))
+
+		if utf8.RuneCountInString(content) > 300 {
+			t.Errorf("%s: content is %d characters, want at most 300", step.name, utf8.RuneCountInString(content))
+		}
+
+		if step.wantContent != "" && content != step.wantContent {
+			t.Errorf("%s: content = %q, want %q", step.name, content, step.wantContent)
+		}
+	}
+
+	current, entries := splitHistory(BodyContent(platform.comments[0]))
+	if current != "shorter" || len(entries) != 1 || !strings.Contains(entries[0], "\n\nshort\n\n") {
+		t.Errorf("got current %q with previous versions %q, want %q with the previous version %q", current, entries, "shorter", short)
+	}
+}
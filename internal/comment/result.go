@@ -0,0 +1,68 @@
+package comment
+
+// Action is the action that was taken on a comment.
+type Action string
+
+const (
+	// ActionCreated means a new comment was created.
+	ActionCreated Action = "created"
+	// ActionUpdated means an existing comment was updated.
+	ActionUpdated Action = "updated"
+	// ActionUnchanged means an existing comment already had the body so it wasn't updated.
+	ActionUnchanged Action = "unchanged"
+	// ActionDeleted means a comment was deleted.
+	ActionDeleted Action = "deleted"
+	// ActionHidden means a comment was hidden.
+	ActionHidden Action = "hidden"
+)
+
+// CommentResult is the action that was taken on a single comment.
+type CommentResult struct {
+	Action Action `json:"action" yaml:"action"`
+	ID     string `json:"id,omitempty" yaml:"id,omitempty"`
+	Ref    string `json:"ref" yaml:"ref"`
+}
+
+// Result is the result of posting or cleaning up comments. Comments contains the action
+// taken on every comment that was affected, in order. Action, ID and Ref are set when a
+// comment is posted, and are the action taken on the posted comment and its ID and
+// reference. If the comment was split into multiple parts, they are for the first part
+// and the action is updated if any of the parts were created or updated.
+type Result struct {
+	Action   Action          `json:"action,omitempty" yaml:"action,omitempty"`
+	ID       string          `json:"id,omitempty" yaml:"id,omitempty"`
+	Ref      string          `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Comments []CommentResult `json:"comments" yaml:"comments"`
+}
+
+// newResult returns an empty result.
+func newResult() *Result {
+	return &Result{Comments: []CommentResult{}}
+}
+
+// add records the action taken on the comment.
+func (r *Result) add(action Action, comment Comment) {
+	r.Comments = append(r.Comments, CommentResult{
+		Action: action,
+		ID:     comment.ID(),
+		Ref:    comment.Ref(),
+	})
+
+	switch action {
+	case ActionCreated, ActionUpdated, ActionUnchanged:
+		if r.Action == "" {
+			r.Action = action
+			r.ID = comment.ID()
+			r.Ref = comment.Ref()
+		} else if r.Action == ActionUnchanged && action != ActionUnchanged {
+			r.Action = ActionUpdated
+		}
+	case ActionDeleted, ActionHidden:
+		// The posted comment is deleted if it's a duplicate of one another job created at the same time
+		if r.Ref == comment.Ref() {
+			r.Action = ""
+			r.ID = ""
+			r.Ref = ""
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// sectionStartMarker and sectionEndMarker are the markdown comments, followed by the
+// section ID, that wrap the content of a section in a comment.
+const (
+	sectionStartMarker = "compost-section "
+	sectionEndMarker   = "compost-section-end "
+)
+
+// validSectionID matches the section IDs that can be embedded in a markdown comment.
+var validSectionID = regexp.MustCompile(`^[A-Za-z0-9_.:/-]+$`)
+
+// sectionBlock wraps the content of a section in the section markers.
+func sectionBlock(id string, content string) string {
+	// Markdown comments must be separated from the content by a blank line
+	return fmt.Sprintf("%s\n\n%s\n\n%s", markdownTag(sectionStartMarker+id), strings.TrimSpace(content), markdownTag(sectionEndMarker+id))
+}
+
+// findSection returns the indexes of the start and end marker lines of the section with
+// the given ID. The end is the last line if the section has no end marker, since the end
+// is cut off if the comment was truncated. It returns -1 if the section doesn't exist.
+func findSection(lines []string, id string) (int, int) {
+	startTag := markdownTag(sectionStartMarker + id)
+	endTag := markdownTag(sectionEndMarker + id)
+
+	start := -1
+
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+
+		if start == -1 && line == startTag {
+			start = i
+			continue
+		}
+
+		if start != -1 && line == endTag {
+			return start, i
+		}
+	}
+
+	if start == -1 {
+		return -1, -1
+	}
+
+	return start, len(lines) - 1
+}
+
+// replaceSection replaces the content of the section with the given ID, keeping the
+// rest of the content unchanged. If the section doesn't exist it is appended, and if
+// it exists more than once the duplicates are removed.
+func replaceSection(s string, id string, content string) string {
+	lines := strings.Split(s, "\n")
+
+	start, end := findSection(lines, id)
+	if start == -1 {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return sectionBlock(id, content)
+		}
+
+		return fmt.Sprintf("%s\n\n%s", s, sectionBlock(id, content))
+	}
+
+	replaced := append(lines[:start:start], sectionBlock(id, content))
+	rest := lines[end+1:]
+
+	for {
+		start, end := findSection(rest, id)
+		if start == -1 {
+			break
+		}
+
+		// The blank lines separating the duplicate from the previous content are removed with it
+		before := rest[:start]
+		for len(before) > 0 && strings.TrimSpace(before[len(before)-1]) == "" {
+			before = before[:len(before)-1]
+		}
+
+		replaced = append(replaced, before...)
+		rest = rest[end+1:]
+	}
+
+	replaced = append(replaced, rest...)
+
+	return strings.Join(replaced, "\n")
+}
+
+// UpdateCommentSection updates the section with the given ID in the latest matching
+// comment, keeping the other sections unchanged. If the section doesn't exist yet it
+// is added to the end of the comment, and if there is no matching comment a new one
+// is created with just this section. This allows multiple jobs to share a comment.
+// If another job changes the comment while it's being updated, the section is
+// applied again to the latest comment so the other job's sections are kept.
+func (h *CommentHandler) UpdateCommentSection(ctx context.Context, section string, body string) (*Result, error) {
+	if !validSectionID.MatchString(section) {
+		return nil, errors.Errorf("Invalid section '%s', it must only contain letters, numbers and the characters _.:/-", section)
+	}
+
+	return retryOnConflict(ctx, func(result *Result) error {
+		latestParts, err := h.latestMatchingParts(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Previous versions kept by UpdateCommentWithHistory are not kept in sections
+		current := ""
+		if len(latestParts) > 0 {
+			current, _ = splitHistory(joinParts(latestParts))
+		}
+
+		log.Ctx(ctx).Info().Msgf("Updating section %s", section)
+
+		return h.updateComment(ctx, result, latestParts, replaceSection(current, section, body))
+	})
+}
@@ -0,0 +1,92 @@
+package comment
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplaceSection(t *testing.T) {
+	startA := "[//]: <> (compost-section a)"
+	endA := "[//]: <> (compost-section-end a)"
+	sectionB := "[//]: <> (compost-section b)\n\nkeep\n\n[//]: <> (compost-section-end b)"
+	newA := startA + "\n\nnew\n\n" + endA
+
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "empty comment",
+			s:    "",
+			want: newA,
+		},
+		{
+			name: "missing section is appended",
+			s:    "intro\n\n" + sectionB,
+			want: "intro\n\n" + sectionB + "\n\n" + newA,
+		},
+		{
+			name: "section with a similar ID is not replaced",
+			s:    "[//]: <> (compost-section ab)\n\nkeep\n\n[//]: <> (compost-section-end ab)",
+			want: "[//]: <> (compost-section ab)\n\nkeep\n\n[//]: <> (compost-section-end ab)\n\n" + newA,
+		},
+		{
+			name: "existing section is replaced",
+			s:    startA + "\n\nold\n\nlines\n\n" + endA + "\n\n" + sectionB,
+			want: newA + "\n\n" + sectionB,
+		},
+		{
+			name: "duplicated section is removed",
+			s:    startA + "\n\nold\n\n" + endA + "\n\n" + sectionB + "\n\n" + startA + "\n\nduplicate\n\n" + endA,
+			want: newA + "\n\n" + sectionB,
+		},
+		{
+			name: "section without end marker is replaced to the end",
+			s:    sectionB + "\n\n" + startA + "\n\ntruncated\n\n*This comment has been truncated.*",
+			want: sectionB + "\n\n" + newA,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceSection(tt.s, "a", "new"); got != tt.want {
+				t.Errorf("replaceSection() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateCommentSectionSplit(t *testing.T) {
+	ctx := testContext()
+	platform := &fakePlatformHandler{maxBodyLength: 1000}
+	h := &CommentHandler{PlatformHandler: platform, Tag: "test"}
+
+	plan := "```\n" + strings.Repeat(resource+"\n", 40) + "```"
+
+	for i := 0; i < 3; i++ {
+		_, err := h.UpdateCommentSection(ctx, "plan", plan)
+		if err != nil {
+			t.Fatalf("UpdateCommentSection() error = %v", err)
+		}
+
+		_, err = h.UpdateCommentSection(ctx, "summary", "1 to add")
+		if err != nil {
+			t.Fatalf("UpdateCommentSection() error = %v", err)
+		}
+
+		parts, err := h.latestMatchingParts(ctx)
+		if err != nil {
+			t.Fatalf("latestMatchingParts() error = %v", err)
+		}
+
+		if len(parts) < 2 {
+			t.Fatalf("got %d parts, want the comment to be split", len(parts))
+		}
+
+		want := sectionBlock("plan", plan) + "\n\n" + sectionBlock("summary", "1 to add")
+		if got := joinParts(parts); got != want {
+			t.Fatalf("run %d: joinParts() = %q, want %q", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package comment
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// fenceReserve is the number of characters reserved in a part for closing and
+// reopening a code fence when a line that is too long is split into chunks.
+const fenceReserve = 16
+
+// splitMarker is the markdown comment, followed by the number of lines added to close
+// the open blocks and the number of lines added to reopen them in the next part, at the
+// end of a part that was split inside a markdown block. It's used to join the parts
+// back into the original body.
+const splitMarker = "compost-split "
+
+// splitMarkerReserve is the number of characters reserved in a part for the split marker.
+const splitMarkerReserve = 32
+
+// splitMarkerTag returns the split marker for a part that ends with the given number of
+// closing lines, followed by a part that starts with the given number of reopening lines.
+func splitMarkerTag(closing int, reopening int) string {
+	return markdownTag(fmt.Sprintf("%s%d %d", splitMarker, closing, reopening))
+}
+
+// parseSplitMarker returns the number of closing and reopening lines from the split
+// marker on the given line. It returns false if the line is not a split marker.
+func parseSplitMarker(line string) (int, int, bool) {
+	tag, ok := parseMarkdownTag(line)
+	if !ok || !strings.HasPrefix(tag, splitMarker) {
+		return 0, 0, false
+	}
+
+	var closing, reopening int
+
+	_, err := fmt.Sscanf(strings.TrimPrefix(tag, splitMarker), "%d %d", &closing, &reopening)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return closing, reopening, true
+}
+
+// partLabel returns the label added to the end of each part of a split comment.
+func partLabel(part int, parts int) string {
+	return fmt.Sprintf("\n\n<sub>Part %d of %d</sub>", part, parts)
+}
+
+// fenceMarker returns the marker of the code fence opened or closed by the given
+// line, e.g. ``` or ~~~~. It returns an empty string if the line is not a code fence.
+func fenceMarker(line string) string {
+	line = strings.TrimSpace(line)
+
+	for _, c := range []string{"`", "~"} {
+		marker := line[:len(line)-len(strings.TrimLeft(line, c))]
+		if len(marker) >= 3 {
+			return marker
+		}
+	}
+
+	return ""
+}
+
+// tableSeparator matches the delimiter row below the header row of a markdown table,
+// e.g. |---|:---:|.
+var tableSeparator = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// isTableRow returns true if the line could be a row of a markdown table.
+func isTableRow(line string) bool {
+	return strings.Contains(line, "|")
+}
+
+// closesFence returns true if the line closes the code fence opened with the given marker.
+func closesFence(line string, marker string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, marker) && strings.Trim(line, marker[:1]) == ""
+}
+
+// markdownState tracks the code fence, details blocks and table that are open at a line
+// of markdown.
+type markdownState struct {
+	fence        string
+	fenceLine    string
+	detailsDepth int
+
+	// tableHeader is the header and delimiter rows of the table the line is in, if any.
+	tableHeader []string
+	lastLine    string
+}
+
+// update updates the state with the given line.
+func (m *markdownState) update(line string) {
+	lastLine := m.lastLine
+	m.lastLine = line
+
+	if m.fence != "" {
+		if closesFence(line, m.fence) {
+			m.fence = ""
+		}
+		return
+	}
+
+	if marker := fenceMarker(line); marker != "" {
+		m.fence = marker
+		m.fenceLine = line
+		m.tableHeader = nil
+		return
+	}
+
+	// A table continues until a blank line or the start of a code fence
+	if strings.TrimSpace(line) == "" {
+		m.tableHeader = nil
+	} else if m.tableHeader == nil && isTableRow(lastLine) && tableSeparator.MatchString(strings.TrimSpace(line)) {
+		m.tableHeader = []string{lastLine, line}
+	}
+
+	lower := strings.ToLower(line)
+	m.detailsDepth += strings.Count(lower, "<details") - strings.Count(lower, "</details>")
+}
+
+// isOpen returns true if a code fence or details block is open.
+func (m markdownState) isOpen() bool {
+	return m.fence != "" || m.detailsDepth > 0
+}
+
+// closing returns the lines needed to close the open code fence and details blocks.
+func (m markdownState) closing() string {
+	var b strings.Builder
+
+	if m.fence != "" {
+		b.WriteString("\n" + m.fence)
+	}
+
+	for i := 0; i < m.detailsDepth; i++ {
+		b.WriteString("\n</details>")
+	}
+
+	return b.String()
+}
+
+// reopening returns the lines needed to reopen the code fence and details blocks that
+// were closed by closing, followed by the header of the table if one is open so the
+// rows that follow are still rendered as a table. The summaries of the details blocks
+// are not repeated.
+func (m markdownState) reopening() []string {
+	var lines []string
+
+	for i := 0; i < m.detailsDepth; i++ {
+		lines = append(lines, "<details>")
+	}
+
+	if m.fence != "" {
+		lines = append(lines, m.fenceLine)
+	}
+
+	return append(lines, m.tableHeader...)
+}
+
+// closeMarkdown closes any code fence and details blocks that are left open at the end
+// of the markdown.
+func closeMarkdown(s string) string {
+	state := markdownState{}
+	for _, line := range strings.Split(s, "\n") {
+		state.update(line)
+	}
+
+	return s + state.closing()
+}
+
+// markdownBlocks splits the markdown into blocks separated by blank lines. Blank
+// lines inside code fences and details blocks don't separate blocks, and tables
+// don't contain blank lines, so splitting between the blocks is always safe.
+func markdownBlocks(s string) []string {
+	var blocks []string
+	var block []string
+
+	state := markdownState{}
+
+	for _, line := range strings.Split(s, "\n") {
+		if !state.isOpen() && strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, strings.Join(block, "\n"))
+				block = nil
+			}
+			continue
+		}
+
+		block = append(block, line)
+		state.update(line)
+	}
+
+	if len(block) > 0 {
+		blocks = append(blocks, strings.Join(block, "\n"))
+	}
+
+	return blocks
+}
+
+// splitLines splits a single markdown block that is too long into parts of at most
+// maxLength characters between lines. If a part ends inside a code fence or details
+// block they are closed and reopened at the beginning of the next part, and if it ends
+// inside a table the table's header is repeated at the beginning of the next part.
+// Every part except the last ends with a split marker so they can be joined again.
+func splitLines(s string, maxLength int) []string {
+	var parts []string
+	var part []string
+	partLength := 0
+	hasContent := false
+
+	// The number of lines at the beginning of the current part that reopen blocks
+	reopened := 0
+
+	// The state of the markdown at the end of the current part
+	state := markdownState{}
+
+	// The number of lines at the end of the current part that open a code fence or table
+	// that has no content yet, and the state before them. These are moved to the next
+	// part so a part doesn't end with an empty code fence or table.
+	opening := 0
+	beforeOpening := markdownState{}
+
+	flush := func(next markdownState) {
+		end := state
+
+		var carried []string
+		if opening > 0 && len(part)-opening > reopened {
+			carried = part[len(part)-opening:]
+			part = part[:len(part)-opening]
+			end = beforeOpening
+		}
+
+		// The table header is only repeated if the next part starts with a row of the
+		// table, and is short enough to leave space for the rows
+		reopen := end
+		if next.tableHeader == nil || utf8.RuneCountInString(strings.Join(end.tableHeader, "\n")) > maxLength/2 {
+			reopen.tableHeader = nil
+		}
+
+		closing := end.closing()
+		reopening := reopen.reopening()
+
+		// Markdown comments must be separated from the content by a blank line
+		marker := splitMarkerTag(strings.Count(closing, "\n"), len(reopening))
+		parts = append(parts, strings.Join(part, "\n")+closing+"\n\n"+marker)
+
+		part = reopening
+		reopened = len(part)
+		part = append(part, carried...)
+		partLength = utf8.RuneCountInString(strings.Join(part, "\n"))
+		hasContent = false
+		opening = 0
+	}
+
+	previous := markdownState{}
+
+	for _, line := range strings.Split(s, "\n") {
+		next := state
+		next.update(line)
+
+		// Space is left for closing the blocks that are still open after the line
+		// and for the split marker
+		closingLength := utf8.RuneCountInString(next.closing()) + splitMarkerReserve
+
+		chunks := splitRunes(line, (maxLength-fenceReserve)/2)
+
+		for _, chunk := range chunks {
+			if hasContent && partLength+1+utf8.RuneCountInString(chunk)+closingLength > maxLength {
+				flush(next)
+			}
+
+			if len(part) > 0 {
+				partLength++
+			}
+
+			part = append(part, chunk)
+			partLength += utf8.RuneCountInString(chunk)
+			hasContent = true
+		}
+
+		switch {
+		case len(chunks) > 1:
+			opening = 0
+		case state.fence == "" && next.fence != "":
+			opening = 1
+			beforeOpening = state
+		case state.tableHeader == nil && next.tableHeader != nil && len(part)-reopened >= 2:
+			// The header row is before the delimiter row
+			opening = 2
+			beforeOpening = previous
+		default:
+			opening = 0
+		}
+
+		previous = state
+		state = next
+	}
+
+	if hasContent {
+		parts = append(parts, strings.Join(part, "\n"))
+	}
+
+	return parts
+}
+
+// splitRunes splits a string into chunks of at most n characters.
+func splitRunes(s string, n int) []string {
+	runes := []rune(s)
+	if len(runes) <= n || n <= 0 {
+		return []string{s}
+	}
+
+	var chunks []string
+	for len(runes) > n {
+		chunks = append(chunks, string(runes[:n]))
+		runes = runes[n:]
+	}
+
+	return append(chunks, string(runes))
+}
+
+// splitBody splits the markdown body into parts of at most maxLength characters.
+// The body is split between markdown blocks, so it is never split inside a code
+// fence, table or details block, unless a single block is longer than maxLength.
+func splitBody(s string, maxLength int) []string {
+	var parts []string
+	part := ""
+
+	for _, block := range markdownBlocks(s) {
+		if part == "" && utf8.RuneCountInString(block) <= maxLength {
+			part = block
+			continue
+		}
+
+		if part != "" && utf8.RuneCountInString(part)+2+utf8.RuneCountInString(block) <= maxLength {
+			part += "\n\n" + block
+			continue
+		}
+
+		if part != "" {
+			parts = append(parts, part)
+			part = ""
+		}
+
+		if utf8.RuneCountInString(block) <= maxLength {
+			part = block
+			continue
+		}
+
+		blockParts := splitLines(block, maxLength)
+		parts = append(parts, blockParts[:len(blockParts)-1]...)
+		part = blockParts[len(blockParts)-1]
+	}
+
+	if part != "" {
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// joinBody joins the parts of a body that was split by splitBody. The lines that were
+// added to close and reopen blocks where a part was split inside a markdown block are
+// removed, so the original body is returned. Lines that were too long for a part and
+// were split into chunks are not joined again.
+func joinBody(parts []string) string {
+	var b strings.Builder
+
+	separator := ""
+	reopened := 0
+
+	for _, part := range parts {
+		lines := strings.Split(part, "\n")
+
+		if reopened > len(lines) {
+			reopened = len(lines)
+		}
+		lines = lines[reopened:]
+
+		next := "\n\n"
+		reopened = 0
+
+		// The marker is separated from the closing lines by a blank line
+		if closing, reopening, ok := parseSplitMarker(lines[len(lines)-1]); ok && len(lines) >= closing+2 {
+			lines = lines[:len(lines)-closing-2]
+			next = "\n"
+			reopened = reopening
+		}
+
+		b.WriteString(separator)
+		b.WriteString(strings.Join(lines, "\n"))
+		separator = next
+	}
+
+	return b.String()
+}
+
+// joinParts returns the content of a comment from its parts, without the tag,
+// metadata and part labels, and without the lines added where it was split.
+func joinParts(parts []Comment) string {
+	contents := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		_, content := splitHeader(part.Body())
+
+		if m := part.Metadata(); m != nil && m.Parts > 1 {
+			content = strings.TrimSuffix(content, partLabel(m.Part, m.Parts))
+		}
+
+		contents = append(contents, strings.TrimSpace(content))
+	}
+
+	return joinBody(contents)
+}
@@ -0,0 +1,208 @@
+package comment
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// resource is a line of plan output used in the split tests.
+const resource = "+ aws_instance.web will be created"
+
+// marker returns the split marker at the end of a part, after the closing lines.
+func marker(closing int, reopening int) string {
+	return "\n\n" + splitMarkerTag(closing, reopening)
+}
+
+func TestSplitBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		maxLength int
+		want      []string
+	}{
+		{
+			name:      "fits in one part",
+			body:      "a\n\nb",
+			maxLength: 100,
+			want:      []string{"a\n\nb"},
+		},
+		{
+			name:      "split between blocks",
+			body:      "aaaa\n\nbbbb\n\ncccc",
+			maxLength: 10,
+			want:      []string{"aaaa\n\nbbbb", "cccc"},
+		},
+		{
+			name:      "fence with blank lines kept together",
+			body:      "intro\n\n```\nx\n\ny\n```",
+			maxLength: 15,
+			want:      []string{"intro", "```\nx\n\ny\n```"},
+		},
+		{
+			name:      "table kept together",
+			body:      "| a | b |\n|---|---|\n| 1 | 2 |\n\nafter",
+			maxLength: 30,
+			want:      []string{"| a | b |\n|---|---|\n| 1 | 2 |", "after"},
+		},
+		{
+			name:      "fence spanning split point",
+			body:      "```hcl\n+ resource 1 will be created\n+ resource 2 will be created\n+ resource 3 will be created\n```",
+			maxLength: 80,
+			want: []string{
+				"```hcl\n+ resource 1 will be created\n```" + marker(1, 1),
+				"```hcl\n+ resource 2 will be created\n```" + marker(1, 1),
+				"```hcl\n+ resource 3 will be created\n```",
+			},
+		},
+		{
+			name:      "table spanning split point",
+			body:      "| name | value |\n|---|---|\n| row 1 | value a |\n| row 2 | value b |\n| row 3 | value c |",
+			maxLength: 80,
+			want: []string{
+				"| name | value |\n|---|---|\n| row 1 | value a |" + marker(0, 2),
+				"| name | value |\n|---|---|\n| row 2 | value b |" + marker(0, 2),
+				"| name | value |\n|---|---|\n| row 3 | value c |",
+			},
+		},
+		{
+			name:      "table ends before split point",
+			body:      "| abc |\n|-----|\n| 1 |\n| 2 |\n| 3 |\n| 4 |\n| 5 |\n| 6 |\n```\n" + resource + "\n```",
+			maxLength: 91,
+			want: []string{
+				"| abc |\n|-----|\n| 1 |\n| 2 |\n| 3 |\n| 4 |\n| 5 |\n| 6 |" + marker(0, 0),
+				"```\n" + resource + "\n```",
+			},
+		},
+		{
+			name:      "table header not left at end of part",
+			body:      "an intro line\n| a |\n|---|\n| 1 | a long table value |\n| 2 | a long table value |",
+			maxLength: 70,
+			want: []string{
+				"an intro line" + marker(0, 0),
+				"| a |\n|---|\n| 1 | a long table value |" + marker(0, 2),
+				"| a |\n|---|\n| 2 | a long table value |",
+			},
+		},
+		{
+			name:      "nested details spanning split point",
+			body:      "<details>\n<summary>Plan</summary>\n\n<details>\n\n" + resource + "\n\n</details>\n\n" + resource + "\n</details>\n\nafter",
+			maxLength: 110,
+			want: []string{
+				"<details>\n<summary>Plan</summary>\n\n<details>\n\n</details>\n</details>" + marker(2, 2),
+				"<details>\n<details>\n" + resource + "\n\n</details>\n\n</details>" + marker(1, 1),
+				"<details>\n" + resource + "\n</details>\n\nafter",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitBody(tt.body, tt.maxLength)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitBody() = %q, want %q", got, tt.want)
+			}
+
+			for _, part := range got {
+				if utf8.RuneCountInString(part) > tt.maxLength {
+					t.Errorf("part %q is longer than %d characters", part, tt.maxLength)
+				}
+			}
+		})
+	}
+}
+
+func TestCloseMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "nothing open",
+			s:    "a\n```\nb\n```",
+			want: "a\n```\nb\n```",
+		},
+		{
+			name: "open fence",
+			s:    "a\n~~~~\nb\n~~~",
+			want: "a\n~~~~\nb\n~~~\n~~~~",
+		},
+		{
+			name: "nested details with open fence",
+			s:    "<details>\n<details>\n```\nb",
+			want: "<details>\n<details>\n```\nb\n```\n</details>\n</details>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closeMarkdown(tt.s); got != tt.want {
+				t.Errorf("closeMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		maxLength int
+	}{
+		{
+			name:      "split between blocks",
+			body:      "aaaa\n\nbbbb\n\ncccc",
+			maxLength: 10,
+		},
+		{
+			name:      "fence closed at end of part",
+			body:      "```\n" + resource + "\n```\n\n```\n" + resource + "\n```",
+			maxLength: 50,
+		},
+		{
+			name:      "fence spanning split point",
+			body:      "intro\n\n```hcl\n+ resource 1 will be created\n+ resource 2 will be created\n+ resource 3 will be created\n```\n\nafter",
+			maxLength: 80,
+		},
+		{
+			name:      "table spanning split point",
+			body:      "| name | value |\n|---|---|\n| row 1 | value a |\n| row 2 | value b |\n| row 3 | value c |",
+			maxLength: 80,
+		},
+		{
+			name:      "table ends before split point",
+			body:      "| abc |\n|-----|\n| 1 |\n| 2 |\n| 3 |\n| 4 |\n| 5 |\n| 6 |\n```\n" + resource + "\n```",
+			maxLength: 91,
+		},
+		{
+			name:      "nested details spanning split point",
+			body:      "<details>\n<summary>Plan</summary>\n\n<details>\n\n" + resource + "\n\n</details>\n\n" + resource + "\n</details>\n\nafter",
+			maxLength: 110,
+		},
+		{
+			name:      "fence in details spanning split point",
+			body:      "<details>\n\n```\n" + resource + "\n" + resource + "\n```\n</details>",
+			maxLength: 90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitBody(tt.body, tt.maxLength)
+			if len(parts) < 2 {
+				t.Fatalf("splitBody() = %q, want multiple parts", parts)
+			}
+
+			got := joinBody(parts)
+			if got != tt.body {
+				t.Errorf("joinBody() = %q, want %q", got, tt.body)
+			}
+
+			if again := splitBody(got, tt.maxLength); !reflect.DeepEqual(again, parts) {
+				t.Errorf("splitBody() of the joined body = %q, want %q", again, parts)
+			}
+		})
+	}
+}
@@ -1,10 +1,23 @@
 package comment
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// markdownTagPrefix and markdownTagSuffix wrap the tag in the markdown comment.
+const (
+	markdownTagPrefix = "[//]: <> ("
+	markdownTagSuffix = ")"
+)
 
 // markdownTag wraps a tag in a markdown comment.
 func markdownTag(s string) string {
-	return fmt.Sprintf("[//]: <> (%s)", s)
+	return fmt.Sprintf("%s%s%s", markdownTagPrefix, s, markdownTagSuffix)
 }
 
 // addMarkdownTag prepends a tag as a markdown comment to the given string.
@@ -17,3 +30,208 @@ func addMarkdownTag(s string, tag string) string {
 
 	return comment
 }
+
+// parseMarkdownTag returns the tag from the markdown comment on the first line
+// of the given string. It returns false if the first line is not a tag marker.
+func parseMarkdownTag(s string) (string, bool) {
+	line := strings.SplitN(s, "\n", 2)[0]
+	line = strings.TrimRight(line, " \t\r")
+
+	if !strings.HasPrefix(line, markdownTagPrefix) || !strings.HasSuffix(line, markdownTagSuffix) {
+		return "", false
+	}
+
+	tag := strings.TrimSuffix(strings.TrimPrefix(line, markdownTagPrefix), markdownTagSuffix)
+	if tag == "" {
+		return "", false
+	}
+
+	return tag, true
+}
+
+// hasMarkdownTag returns true if the given string starts with the marker line
+// for exactly the given tag. A tag that is only contained in the string, e.g.
+// a longer tag or a quote of a tagged comment, does not match.
+func hasMarkdownTag(s string, tag string) bool {
+	t, ok := parseMarkdownTag(s)
+	return ok && t == tag
+}
+
+// metadataVersion is the version of the metadata format. It is embedded in the
+// metadata so that the format can be changed without breaking older comments.
+const metadataVersion = 1
+
+// metadataMarkerPrefix is the prefix of the markdown comment containing the metadata.
+const metadataMarkerPrefix = "compost-metadata "
+
+// Metadata is the hidden metadata embedded in a comment. It records what produced the
+// comment so comments can be filtered without parsing the body. If the comment was split
+// into multiple parts, Part and Parts are set and the BodyHash is the hash of the full
+// body, so it is shared by all the parts.
+type Metadata struct {
+	Version        int               `json:"version" yaml:"version"`
+	Tag            string            `json:"tag,omitempty" yaml:"tag,omitempty"`
+	CompostVersion string            `json:"compostVersion,omitempty" yaml:"compostVersion,omitempty"`
+	CommitSHA      string            `json:"commitSha,omitempty" yaml:"commitSha,omitempty"`
+	PipelineURL    string            `json:"pipelineUrl,omitempty" yaml:"pipelineUrl,omitempty"`
+	PostedAt       string            `json:"postedAt,omitempty" yaml:"postedAt,omitempty"`
+	BodyHash       string            `json:"bodyHash,omitempty" yaml:"bodyHash,omitempty"`
+	Part           int               `json:"part,omitempty" yaml:"part,omitempty"`
+	Parts          int               `json:"parts,omitempty" yaml:"parts,omitempty"`
+	Values         map[string]string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// bodyHash returns the hash of a comment body that is stored in the metadata.
+func bodyHash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// metadataTag encodes the metadata in a markdown comment. The JSON is base64 encoded
+// so it can't contain any characters that would break the markdown comment.
+func metadataTag(m Metadata) (string, error) {
+	m.Version = metadataVersion
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	return markdownTag(metadataMarkerPrefix + base64.RawURLEncoding.EncodeToString(b)), nil
+}
+
+// addMetadata prepends the metadata as a markdown comment to the given string.
+func addMetadata(s string, m Metadata) (string, error) {
+	tag, err := metadataTag(m)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s\n%s", tag, s), nil
+}
+
+// splitHeader splits the given string into the header, which is the tag and the metadata
+// and hidden markdown comments that follow it, and the rest of the string. Other markdown
+// comments, e.g. the section markers, are part of the content even if it starts with them.
+func splitHeader(s string) ([]string, string) {
+	lines := strings.Split(s, "\n")
+
+	i := 0
+	for i < len(lines) {
+		tag, ok := parseMarkdownTag(lines[i])
+		if !ok || (i > 0 && !strings.HasPrefix(tag, metadataMarkerPrefix) && tag != hiddenMarker) {
+			break
+		}
+		i++
+	}
+
+	return lines[:i], strings.Join(lines[i:], "\n")
+}
+
+// BodyContent returns the body of the comment without the tag and metadata.
+func BodyContent(c Comment) string {
+	_, content := splitHeader(c.Body())
+	return content
+}
+
+// addTagAndMetadata prepends the tag and metadata as markdown comments to the given string.
+func addTagAndMetadata(s string, tag string, m Metadata) (string, error) {
+	withMetadata, err := addMetadata(s, m)
+	if err != nil {
+		return "", err
+	}
+
+	return addMarkdownTag(withMetadata, tag), nil
+}
+
+// headerTags returns the values of the markdown comments at the beginning of the given string.
+func headerTags(s string) []string {
+	header, _ := splitHeader(s)
+
+	tags := make([]string, 0, len(header))
+	for _, line := range header {
+		tag, _ := parseMarkdownTag(line)
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// parseMetadata returns the metadata embedded in the markdown comments at the
+// beginning of the given string. It returns nil if the string has no metadata
+// or the metadata can't be decoded.
+func parseMetadata(s string) *Metadata {
+	tags := headerTags(s)
+
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, metadataMarkerPrefix) {
+			continue
+		}
+
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(tag, metadataMarkerPrefix))
+		if err != nil {
+			return nil
+		}
+
+		var m Metadata
+		err = json.Unmarshal(b, &m)
+		if err != nil {
+			return nil
+		}
+
+		return &m
+	}
+
+	return nil
+}
+
+// stripMetadata removes the metadata markdown comment from the given string.
+func stripMetadata(s string) string {
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+		tag, ok := parseMarkdownTag(line)
+		if !ok {
+			break
+		}
+
+		if strings.HasPrefix(tag, metadataMarkerPrefix) {
+			return strings.Join(append(lines[:i:i], lines[i+1:]...), "\n")
+		}
+	}
+
+	return s
+}
+
+// hiddenMarker is the markdown comment added to comments that have been hidden by
+// rewriting the body, for platforms that don't support hiding comments.
+const hiddenMarker = "compost-hidden"
+
+// hideBody collapses the body of the given comment into a details block marked as
+// outdated, keeping the tag and metadata at the beginning so the comment still matches.
+// The hidden marker is added to the header so the hidden state can be detected.
+func hideBody(s string) string {
+	if isHiddenBody(s) {
+		return s
+	}
+
+	header, content := splitHeader(s)
+	header = append(header, markdownTag(hiddenMarker))
+
+	return fmt.Sprintf(
+		"%s\n<details>\n<summary>Outdated</summary>\n\n%s\n\n</details>",
+		strings.Join(header, "\n"),
+		strings.TrimSpace(content),
+	)
+}
+
+// isHiddenBody returns true if the given comment body has been hidden by hideBody.
+func isHiddenBody(s string) bool {
+	for _, tag := range headerTags(s) {
+		if tag == hiddenMarker {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package comment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitHeader(t *testing.T) {
+	metadata := "[//]: <> (compost-metadata eyJ2ZXJzaW9uIjoxfQ)"
+
+	tests := []struct {
+		name        string
+		s           string
+		wantHeader  []string
+		wantContent string
+	}{
+		{
+			name:        "no header",
+			s:           "body",
+			wantHeader:  []string{},
+			wantContent: "body",
+		},
+		{
+			name:        "tag and metadata",
+			s:           "[//]: <> (plan)\n" + metadata + "\nbody",
+			wantHeader:  []string{"[//]: <> (plan)", metadata},
+			wantContent: "body",
+		},
+		{
+			name:        "hidden",
+			s:           "[//]: <> (plan)\n" + metadata + "\n[//]: <> (compost-hidden)\n<details>",
+			wantHeader:  []string{"[//]: <> (plan)", metadata, "[//]: <> (compost-hidden)"},
+			wantContent: "<details>",
+		},
+		{
+			name:        "content starting with a section",
+			s:           "[//]: <> (plan)\n" + metadata + "\n[//]: <> (compost-section a)\n\nbody",
+			wantHeader:  []string{"[//]: <> (plan)", metadata},
+			wantContent: "[//]: <> (compost-section a)\n\nbody",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, content := splitHeader(tt.s)
+
+			if !reflect.DeepEqual(header, tt.wantHeader) {
+				t.Errorf("splitHeader() header = %q, want %q", header, tt.wantHeader)
+			}
+
+			if content != tt.wantContent {
+				t.Errorf("splitHeader() content = %q, want %q", content, tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package comment
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TruncateOptions are the options for truncating a body that is too long for a single
+// comment instead of splitting it into multiple parts.
+type TruncateOptions struct {
+	// MaxLength is the maximum length of the body. If it's not set, or is more than the
+	// platform allows, the maximum length the platform allows is used.
+	MaxLength int
+
+	// Footer is appended to the body if it is truncated.
+	Footer string
+}
+
+// TruncateBody truncates the markdown body so that it is at most maxLength characters,
+// including the footer, which is appended if the body is truncated. The body is truncated
+// between lines, and any code fences or details blocks that are open at that point are
+// closed so the truncated body is still valid markdown.
+func TruncateBody(s string, maxLength int, footer string) string {
+	if utf8.RuneCountInString(s) <= maxLength {
+		return s
+	}
+
+	if footer != "" {
+		footer = "\n\n" + footer
+	}
+	footerLength := utf8.RuneCountInString(footer)
+
+	var kept []string
+	length := 0
+	state := markdownState{}
+
+	for _, line := range strings.Split(s, "\n") {
+		lineLength := utf8.RuneCountInString(line)
+		if len(kept) > 0 {
+			lineLength++
+		}
+
+		next := state
+		next.update(line)
+
+		if length+lineLength+utf8.RuneCountInString(next.closing())+footerLength > maxLength {
+			break
+		}
+
+		kept = append(kept, line)
+		length += lineLength
+		state = next
+	}
+
+	// If even the first line is too long it's cut at the maximum length
+	if len(kept) == 0 {
+		runes := []rune(s)
+		n := maxLength - footerLength
+		if n < 0 {
+			n = 0
+		}
+		return string(runes[:n]) + footer
+	}
+
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n") + state.closing() + footer
+}
@@ -0,0 +1,76 @@
+package comment
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		maxLength int
+		footer    string
+		want      string
+	}{
+		{
+			name:      "not truncated",
+			body:      "short",
+			maxLength: 100,
+			footer:    "truncated",
+			want:      "short",
+		},
+		{
+			name:      "truncated between lines",
+			body:      "aaaa\nbbbb\ncccc",
+			maxLength: 9,
+			want:      "aaaa\nbbbb",
+		},
+		{
+			name:      "fence open at truncation point",
+			body:      "intro\n```\nline 1\nline 2\nline 3\n```\nafter",
+			maxLength: 25,
+			want:      "intro\n```\nline 1\n```",
+		},
+		{
+			name:      "fence open at truncation point with footer",
+			body:      "intro\n```\nline 1\nline 2\nline 3\n```\nafter",
+			maxLength: 30,
+			footer:    "cut",
+			want:      "intro\n```\nline 1\n```\n\ncut",
+		},
+		{
+			name:      "nested details open at truncation point",
+			body:      "<details>\n<details>\nx\ny\n</details>\n</details>",
+			maxLength: 42,
+			want:      "<details>\n<details>\n</details>\n</details>",
+		},
+		{
+			name:      "first line too long",
+			body:      "0123456789",
+			maxLength: 5,
+			want:      "01234",
+		},
+		{
+			name:      "first line too long with footer",
+			body:      "0123456789",
+			maxLength: 8,
+			footer:    "F",
+			want:      "01234\n\nF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateBody(tt.body, tt.maxLength, tt.footer)
+
+			if got != tt.want {
+				t.Errorf("TruncateBody() = %q, want %q", got, tt.want)
+			}
+
+			if utf8.RuneCountInString(got) > tt.maxLength {
+				t.Errorf("TruncateBody() is longer than %d characters", tt.maxLength)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package detect
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 )
@@ -14,17 +16,29 @@ type DetectOptions struct {
 
 	// TargetType limits the detection to find only environments for the given target type (pull request or commit SHA)
 	TargetType string
+
+	// BitbucketTokenEnvVar is the name of the environment variable containing the Bitbucket token.
+	// If not set, BITBUCKET_TOKEN is used.
+	BitbucketTokenEnvVar string
+
+	// Detectors limits the detection to the detectors with the given names, checked in
+	// the given order. If not set, all detectors are checked in the default order.
+	Detectors []string
 }
 
 // DetectResult contains the result of a detection.
 // It contains the platform, project (repo), target type and target ref (pull request number/commit SHA).
 // It also contains any extra values the detector can detect, e.g. a token or API URL.
+// The commit SHA and pipeline URL are embedded in the comment metadata, they are empty
+// if the detector can't detect them.
 type DetectResult struct {
-	Platform   string
-	Project    string
-	TargetType string
-	TargetRef  string
-	Extra      interface{}
+	Platform    string
+	Project     string
+	TargetType  string
+	TargetRef   string
+	Extra       interface{}
+	CommitSHA   string
+	PipelineURL string
 }
 
 // DetectError is an error that is returned when the environment could not be detected
@@ -45,8 +59,9 @@ type Detector interface {
 }
 
 // DetectorRegistryItem represents an item in the detector registry.
-// It maps the detector to the platforms it detects.
+// It maps the detector to its name and the platforms it detects.
 type detectorRegistryItem struct {
+	name               string
 	supportedPlatforms []string
 	detector           Detector
 }
@@ -57,19 +72,79 @@ type detectorRegistryItem struct {
 var detectorRegistry = []detectorRegistryItem{}
 
 // registerDetector registers a new detector in the detector registry,
-// mapping it to the platforms it detects.
-func registerDetector(supportedPlatforms []string, detector Detector) {
+// mapping it to its name and the platforms it detects.
+func registerDetector(name string, supportedPlatforms []string, detector Detector) {
 	detectorRegistry = append(detectorRegistry, detectorRegistryItem{
+		name:               name,
+		supportedPlatforms: supportedPlatforms,
+		detector:           detector,
+	})
+}
+
+// fallbackDetectorRegistry contains the list of detectors that are only checked
+// if none of the detectors in the detectorRegistry detect the environment.
+var fallbackDetectorRegistry = []detectorRegistryItem{}
+
+// registerFallbackDetector registers a new detector in the fallback detector registry,
+// mapping it to its name and the platforms it detects.
+func registerFallbackDetector(name string, supportedPlatforms []string, detector Detector) {
+	fallbackDetectorRegistry = append(fallbackDetectorRegistry, detectorRegistryItem{
+		name:               name,
 		supportedPlatforms: supportedPlatforms,
 		detector:           detector,
 	})
 }
 
+// DetectorNames returns the names of all the detectors in the default order.
+func DetectorNames() []string {
+	names := []string{}
+	for _, item := range append(append([]detectorRegistryItem{}, detectorRegistry...), fallbackDetectorRegistry...) {
+		names = append(names, item.name)
+	}
+	return names
+}
+
+// orderedDetectors returns the detectors to check in order of precedence. If
+// opts.Detectors is set only those detectors are returned, in the given order.
+// Otherwise all the detectors are returned, followed by the fallback detectors.
+func orderedDetectors(opts DetectOptions) ([]detectorRegistryItem, error) {
+	registry := append(append([]detectorRegistryItem{}, detectorRegistry...), fallbackDetectorRegistry...)
+
+	if len(opts.Detectors) == 0 {
+		return registry, nil
+	}
+
+	items := make([]detectorRegistryItem, 0, len(opts.Detectors))
+
+	for _, name := range opts.Detectors {
+		found := false
+
+		for _, item := range registry {
+			if item.name == name {
+				items = append(items, item)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("Invalid detector '%s', valid options are %s", name, strings.Join(DetectorNames(), ", "))
+		}
+	}
+
+	return items, nil
+}
+
 // DetectEnvironment detects the environment for a given platform and target type.
-// It iterates through the detectors and returns the first one that detects the
-// environment.
+// It iterates through the detectors in order of precedence and returns the first
+// one that detects the environment.
 func DetectEnvironment(ctx context.Context, opts DetectOptions) (DetectResult, error) {
-	for _, detectorRegistryItem := range detectorRegistry {
+	registry, err := orderedDetectors(opts)
+	if err != nil {
+		return DetectResult{}, err
+	}
+
+	for _, detectorRegistryItem := range registry {
 		if opts.Platform != "" && !contains(detectorRegistryItem.supportedPlatforms, opts.Platform) {
 			continue
 		}
@@ -95,6 +170,76 @@ func DetectEnvironment(ctx context.Context, opts DetectOptions) (DetectResult, e
 	return DetectResult{}, &DetectError{errors.New("Could not to detect environment")}
 }
 
+// DetectorExplanation explains the outcome of running a detector.
+type DetectorExplanation struct {
+	Name               string
+	DisplayName        string
+	SupportedPlatforms []string
+
+	// Skipped is true if the detector was not run because it doesn't support the platform.
+	Skipped bool
+
+	// EnvVars contains the environment variables checked by the detector, in the
+	// order they were checked. Secret values are masked.
+	EnvVars []EnvVarCheck
+
+	// Result is the result of the detection, or nil if the environment was not detected.
+	Result *DetectResult
+
+	// Err is the reason the environment was not detected.
+	Err error
+
+	// Selected is true if this is the detector whose result would be used by DetectEnvironment.
+	Selected bool
+}
+
+// ExplainEnvironment runs every detector in order of precedence and returns an explanation
+// of each outcome, including the environment variables that were checked. Unlike
+// DetectEnvironment it does not stop at the first detector that detects the environment.
+func ExplainEnvironment(ctx context.Context, opts DetectOptions) ([]DetectorExplanation, error) {
+	registry, err := orderedDetectors(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	explanations := make([]DetectorExplanation, 0, len(registry))
+	selected := false
+
+	for _, item := range registry {
+		explanation := DetectorExplanation{
+			Name:               item.name,
+			DisplayName:        item.detector.DisplayName(),
+			SupportedPlatforms: item.supportedPlatforms,
+		}
+
+		if opts.Platform != "" && !contains(item.supportedPlatforms, opts.Platform) {
+			explanation.Skipped = true
+			explanations = append(explanations, explanation)
+			continue
+		}
+
+		detectCtx, recorder := withEnvVarRecorder(ctx)
+
+		result, err := item.detector.Detect(detectCtx, opts)
+		explanation.EnvVars = recorder.checks
+
+		if err != nil {
+			explanation.Err = err
+		} else {
+			explanation.Result = &result
+
+			if !selected {
+				explanation.Selected = true
+				selected = true
+			}
+		}
+
+		explanations = append(explanations, explanation)
+	}
+
+	return explanations, nil
+}
+
 // contains returns true if the given string slice contains the given string.
 func contains(a []string, s string) bool {
 	for _, e := range a {
@@ -0,0 +1,185 @@
+package detect
+
+import (
+	"compost/internal/comment"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// AzurePipelinesDetector detects an Azure Pipelines environment. It supports
+// pipelines building repositories hosted on Azure Repos or GitHub.
+type AzurePipelinesDetector struct{}
+
+// DisplayName is the display name to use in any logs or output for this detector.
+func (d *AzurePipelinesDetector) DisplayName() string {
+	return "Azure Pipelines"
+}
+
+// Detect checks the environment variables to determine if it is running in
+// an Azure Pipelines environment. If it is it returns a DetectResult, otherwise
+// it throws a DetectError.
+//
+// If the repository provider is GitHub it returns a result for the GitHub platform,
+// otherwise it returns a result for the Azure Repos platform.
+func (d *AzurePipelinesDetector) Detect(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	err := checkEnvVarValue(ctx, "TF_BUILD", "True", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	provider, err := checkEnvVarExists(ctx, "BUILD_REPOSITORY_PROVIDER", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	if provider == "GitHub" {
+		return d.detectGitHub(ctx, opts)
+	}
+
+	if provider == "TfsGit" {
+		return d.detectAzureRepos(ctx, opts)
+	}
+
+	return DetectResult{}, &DetectError{fmt.Errorf("Repository provider %s is not supported", provider)}
+}
+
+// detectAzureRepos returns the DetectResult for a pipeline building an Azure Repos repository.
+// Azure Repos only supports pull request comments.
+func (d *AzurePipelinesDetector) detectAzureRepos(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	if opts.Platform != "" && opts.Platform != "azure-repos" {
+		return DetectResult{}, &DetectError{errors.New("Repository provider is Azure Repos")}
+	}
+
+	if opts.TargetType == "commit" {
+		return DetectResult{}, &DetectError{errors.New("Azure Repos does not support commit comments")}
+	}
+
+	token, err := checkEnvVarExists(ctx, "SYSTEM_ACCESSTOKEN", true)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	collectionURI, err := checkEnvVarExists(ctx, "SYSTEM_COLLECTIONURI", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	teamProject, err := checkEnvVarExists(ctx, "SYSTEM_TEAMPROJECT", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	repoName, err := checkEnvVarExists(ctx, "BUILD_REPOSITORY_NAME", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	targetRef, err := checkEnvVarExists(ctx, "SYSTEM_PULLREQUEST_PULLREQUESTID", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	project := fmt.Sprintf("%s/%s/_git/%s", strings.TrimSuffix(collectionURI, "/"), teamProject, repoName)
+
+	commitSHA := lookupEnvVar(ctx, "SYSTEM_PULLREQUEST_SOURCECOMMITID", false)
+
+	return DetectResult{
+		Platform:   "azure-repos",
+		Project:    project,
+		TargetType: "pull-request",
+		TargetRef:  targetRef,
+		Extra: comment.AzureReposExtra{
+			Token: token,
+		},
+		CommitSHA:   commitSHA,
+		PipelineURL: d.pipelineURL(ctx),
+	}, nil
+}
+
+// detectGitHub returns the DetectResult for a pipeline building a GitHub repository.
+//
+// If the pipeline is running in the context of a pull request it returns a
+// target type of pull-request and the the pull request number as the target ref.
+// Otherwise it returns a target type of commit and the commit SHA.
+func (d *AzurePipelinesDetector) detectGitHub(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	if opts.Platform != "" && opts.Platform != "github" {
+		return DetectResult{}, &DetectError{errors.New("Repository provider is GitHub")}
+	}
+
+	token, err := checkEnvVarExists(ctx, "GITHUB_TOKEN", true)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	project, err := checkEnvVarExists(ctx, "BUILD_REPOSITORY_NAME", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	apiURL := lookupEnvVar(ctx, "GITHUB_API_URL", false)
+
+	var targetType string
+	var targetRef string
+
+	if opts.TargetType == "" || opts.TargetType == "pull-request" {
+		targetRef = lookupEnvVar(ctx, "SYSTEM_PULLREQUEST_PULLREQUESTNUMBER", false)
+		if targetRef != "" {
+			targetType = "pull-request"
+		}
+	}
+
+	if targetRef == "" && opts.TargetType == "" || opts.TargetType == "commit" {
+		targetType = "commit"
+		targetRef = lookupEnvVar(ctx, "SYSTEM_PULLREQUEST_SOURCECOMMITID", false)
+		if targetRef == "" {
+			targetRef, err = checkEnvVarExists(ctx, "BUILD_SOURCEVERSION", false)
+			if err != nil {
+				return DetectResult{}, &DetectError{err}
+			}
+		}
+	}
+
+	if targetRef == "" {
+		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
+	}
+
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA = lookupEnvVar(ctx, "SYSTEM_PULLREQUEST_SOURCECOMMITID", false)
+	}
+
+	return DetectResult{
+		Platform:   "github",
+		Project:    project,
+		TargetType: targetType,
+		TargetRef:  targetRef,
+		Extra: comment.GitHubExtra{
+			APIURL: apiURL,
+			Token:  token,
+		},
+		CommitSHA:   commitSHA,
+		PipelineURL: d.pipelineURL(ctx),
+	}, nil
+}
+
+// pipelineURL returns the URL of the build results page, or an empty string if it
+// can't be determined.
+func (d *AzurePipelinesDetector) pipelineURL(ctx context.Context) string {
+	collectionURI := lookupEnvVar(ctx, "SYSTEM_COLLECTIONURI", false)
+	teamProject := lookupEnvVar(ctx, "SYSTEM_TEAMPROJECT", false)
+	buildID := lookupEnvVar(ctx, "BUILD_BUILDID", false)
+
+	if collectionURI == "" || teamProject == "" || buildID == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/%s/_build/results?buildId=%s", strings.TrimSuffix(collectionURI, "/"), url.PathEscape(teamProject), buildID)
+}
+
+func init() {
+	// Here we register the detectors against the platforms they detect
+	registerDetector("azure-pipelines", []string{"azure-repos", "github"}, &AzurePipelinesDetector{})
+}
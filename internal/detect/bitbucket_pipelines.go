@@ -0,0 +1,112 @@
+package detect
+
+import (
+	"compost/internal/comment"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultBitbucketTokenEnvVar is the environment variable the Bitbucket token is read
+// from if DetectOptions.BitbucketTokenEnvVar is not set.
+var defaultBitbucketTokenEnvVar = "BITBUCKET_TOKEN"
+
+// bitbucketToken reads the Bitbucket token from the environment variable configured in the
+// options. The token can be a repository/workspace access token or an app password. App
+// passwords can be given in the form username:app-password, or the username can be set
+// with the BITBUCKET_USERNAME environment variable.
+func bitbucketToken(ctx context.Context, opts DetectOptions) (string, error) {
+	envVar := opts.BitbucketTokenEnvVar
+	if envVar == "" {
+		envVar = defaultBitbucketTokenEnvVar
+	}
+
+	token, err := checkEnvVarExists(ctx, envVar, true)
+	if err != nil {
+		return "", err
+	}
+
+	username := lookupEnvVar(ctx, "BITBUCKET_USERNAME", false)
+	if username != "" && !strings.Contains(token, ":") {
+		token = fmt.Sprintf("%s:%s", username, token)
+	}
+
+	return token, nil
+}
+
+// BitbucketPipelinesDetector detects a Bitbucket Pipelines environment.
+type BitbucketPipelinesDetector struct{}
+
+// DisplayName is the display name to use in any logs or output for this detector.
+func (d *BitbucketPipelinesDetector) DisplayName() string {
+	return "Bitbucket Pipelines"
+}
+
+// Detect checks the environment variables to determine if it is running in
+// a Bitbucket Pipelines environment. If it is it returns a DetectResult, otherwise
+// it throws a DetectError.
+//
+// If the pipeline is running in the context of a pull request it returns a
+// target type of pull-request and the the pull request number as the target ref.
+// Otherwise it returns a target type of commit and the commit SHA.
+func (d *BitbucketPipelinesDetector) Detect(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	buildNumber, err := checkEnvVarExists(ctx, "BITBUCKET_BUILD_NUMBER", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	token, err := bitbucketToken(ctx, opts)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	project, err := checkEnvVarExists(ctx, "BITBUCKET_REPO_FULL_NAME", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	var targetType string
+	var targetRef string
+
+	if opts.TargetType == "" || opts.TargetType == "pull-request" {
+		targetRef = lookupEnvVar(ctx, "BITBUCKET_PR_ID", false)
+		if targetRef != "" {
+			targetType = "pull-request"
+		}
+	}
+
+	if targetRef == "" && opts.TargetType == "" || opts.TargetType == "commit" {
+		targetType = "commit"
+		targetRef, err = checkEnvVarExists(ctx, "BITBUCKET_COMMIT", false)
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+	}
+
+	if targetRef == "" {
+		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
+	}
+
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA = lookupEnvVar(ctx, "BITBUCKET_COMMIT", false)
+	}
+
+	return DetectResult{
+		Platform:   "bitbucket",
+		Project:    project,
+		TargetType: targetType,
+		TargetRef:  targetRef,
+		Extra: comment.BitbucketExtra{
+			Token: token,
+		},
+		CommitSHA:   commitSHA,
+		PipelineURL: fmt.Sprintf("https://bitbucket.org/%s/pipelines/results/%s", project, buildNumber),
+	}, nil
+}
+
+func init() {
+	// Here we register the detectors against the platforms they detect
+	registerDetector("bitbucket-pipelines", []string{"bitbucket"}, &BitbucketPipelinesDetector{})
+}
@@ -0,0 +1,98 @@
+package detect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BuildkiteDetector detects a Buildkite environment. It supports pipelines
+// building repositories hosted on GitHub, GitLab or Bitbucket.
+type BuildkiteDetector struct{}
+
+// DisplayName is the display name to use in any logs or output for this detector.
+func (d *BuildkiteDetector) DisplayName() string {
+	return "Buildkite"
+}
+
+// Detect checks the environment variables to determine if it is running in
+// a Buildkite environment. If it is it returns a DetectResult, otherwise
+// it throws a DetectError.
+//
+// The platform and project are determined from the repository URL. If the build
+// is running in the context of a pull request it returns a target type of pull-request
+// and the the pull request number as the target ref. Otherwise it returns a target
+// type of commit and the commit SHA.
+func (d *BuildkiteDetector) Detect(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	err := checkEnvVarValue(ctx, "BUILDKITE", "true", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	repoURL, err := checkEnvVarExists(ctx, "BUILDKITE_REPO", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	// BUILDKITE_PULL_REQUEST is set to "false" if the build is not for a pull request
+	prNumber := lookupEnvVar(ctx, "BUILDKITE_PULL_REQUEST", false)
+	if prNumber == "false" {
+		prNumber = ""
+	}
+
+	var targetType string
+	var targetRef string
+
+	if opts.TargetType == "" || opts.TargetType == "pull-request" {
+		targetRef = prNumber
+		if targetRef != "" {
+			targetType = "pull-request"
+		}
+	}
+
+	if targetRef == "" && opts.TargetType == "" || opts.TargetType == "commit" {
+		targetType = "commit"
+		targetRef, err = checkEnvVarExists(ctx, "BUILDKITE_COMMIT", false)
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+	}
+
+	if targetRef == "" {
+		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
+	}
+
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA = lookupEnvVar(ctx, "BUILDKITE_COMMIT", false)
+	}
+
+	info, err := repoInfoFromGitURL(repoURL)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	if opts.Platform != "" && opts.Platform != info.Platform {
+		return DetectResult{}, &DetectError{fmt.Errorf("Repository platform is %s", info.Platform)}
+	}
+
+	extra, err := repoExtra(ctx, opts, info)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	return DetectResult{
+		Platform:    info.Platform,
+		Project:     info.Project,
+		TargetType:  targetType,
+		TargetRef:   targetRef,
+		Extra:       extra,
+		CommitSHA:   commitSHA,
+		PipelineURL: lookupEnvVar(ctx, "BUILDKITE_BUILD_URL", false),
+	}, nil
+}
+
+func init() {
+	// Here we register the detectors against the platforms they detect
+	registerDetector("buildkite", []string{"github", "gitlab", "bitbucket", "bitbucket-server"}, &BuildkiteDetector{})
+}
@@ -0,0 +1,125 @@
+package detect
+
+import (
+	"compost/internal/comment"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CircleCIDetector detects a CircleCI environment. It supports projects
+// hosted on GitHub or Bitbucket Cloud.
+type CircleCIDetector struct{}
+
+// DisplayName is the display name to use in any logs or output for this detector.
+func (d *CircleCIDetector) DisplayName() string {
+	return "CircleCI"
+}
+
+// Detect checks the environment variables to determine if it is running in
+// a CircleCI environment. If it is it returns a DetectResult, otherwise
+// it throws a DetectError.
+//
+// The platform is determined from the host of the repository URL. If the job is
+// running in the context of a pull request it returns a target type of pull-request
+// and the the pull request number as the target ref. Otherwise it returns a target
+// type of commit and the commit SHA.
+func (d *CircleCIDetector) Detect(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	err := checkEnvVarValue(ctx, "CIRCLECI", "true", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	repoURL, err := checkEnvVarExists(ctx, "CIRCLE_REPOSITORY_URL", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	u, err := parseGitURL(repoURL)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	platform := platformForHost(u.Host)
+	if platform != "github" && platform != "bitbucket" {
+		return DetectResult{}, &DetectError{fmt.Errorf("Repository host %s is not supported", u.Host)}
+	}
+
+	if opts.Platform != "" && opts.Platform != platform {
+		return DetectResult{}, &DetectError{fmt.Errorf("Repository platform is %s", platform)}
+	}
+
+	owner, err := checkEnvVarExists(ctx, "CIRCLE_PROJECT_USERNAME", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	repo, err := checkEnvVarExists(ctx, "CIRCLE_PROJECT_REPONAME", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	var extra interface{}
+
+	if platform == "github" {
+		token, err := checkEnvVarExists(ctx, "GITHUB_TOKEN", true)
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+
+		extra = comment.GitHubExtra{
+			Token: token,
+		}
+	} else {
+		token, err := bitbucketToken(ctx, opts)
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+
+		extra = comment.BitbucketExtra{
+			Token: token,
+		}
+	}
+
+	var targetType string
+	var targetRef string
+
+	if opts.TargetType == "" || opts.TargetType == "pull-request" {
+		targetRef = pullRequestNumberFromURL(lookupEnvVar(ctx, "CIRCLE_PULL_REQUEST", false))
+		if targetRef != "" {
+			targetType = "pull-request"
+		}
+	}
+
+	if targetRef == "" && opts.TargetType == "" || opts.TargetType == "commit" {
+		targetType = "commit"
+		targetRef, err = checkEnvVarExists(ctx, "CIRCLE_SHA1", false)
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+	}
+
+	if targetRef == "" {
+		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
+	}
+
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA = lookupEnvVar(ctx, "CIRCLE_SHA1", false)
+	}
+
+	return DetectResult{
+		Platform:    platform,
+		Project:     fmt.Sprintf("%s/%s", owner, repo),
+		TargetType:  targetType,
+		TargetRef:   targetRef,
+		Extra:       extra,
+		CommitSHA:   commitSHA,
+		PipelineURL: lookupEnvVar(ctx, "CIRCLE_BUILD_URL", false),
+	}, nil
+}
+
+func init() {
+	// Here we register the detectors against the platforms they detect
+	registerDetector("circleci", []string{"github", "bitbucket"}, &CircleCIDetector{})
+}
@@ -18,18 +18,67 @@ func sanitizeValue(val string, isSecret bool) string {
 	return val
 }
 
+// EnvVarCheck is a record of an environment variable that was checked by a detector.
+type EnvVarCheck struct {
+	Name string
+	// Value is the sanitized value of the environment variable.
+	Value string
+	Found bool
+}
+
+// envVarRecorderKey is the context key for the envVarRecorder.
+type envVarRecorderKey struct{}
+
+// envVarRecorder records the environment variables checked by a detector.
+type envVarRecorder struct {
+	checks []EnvVarCheck
+}
+
+// withEnvVarRecorder returns a new context with an envVarRecorder attached to it.
+func withEnvVarRecorder(ctx context.Context) (context.Context, *envVarRecorder) {
+	r := &envVarRecorder{}
+	return context.WithValue(ctx, envVarRecorderKey{}, r), r
+}
+
+// recordEnvVar records the environment variable check in the context's envVarRecorder, if any.
+func recordEnvVar(ctx context.Context, name string, val string, isSecret bool) {
+	r, ok := ctx.Value(envVarRecorderKey{}).(*envVarRecorder)
+	if !ok {
+		return
+	}
+
+	check := EnvVarCheck{Name: name, Found: val != ""}
+	if check.Found {
+		check.Value = sanitizeValue(val, isSecret)
+	}
+
+	r.checks = append(r.checks, check)
+}
+
+// lookupEnvVar returns the value of an optional environment variable, or an empty
+// string if it is not set.
+// If the variable is a secret, any logs will be replaced with a placeholder.
+func lookupEnvVar(ctx context.Context, name string, isSecret bool) string {
+	val := os.Getenv(name)
+	recordEnvVar(ctx, name, val, isSecret)
+
+	if val != "" {
+		log.Ctx(ctx).Debug().Msgf("%s environment variable is set to %s", name, sanitizeValue(val, isSecret))
+	}
+
+	return val
+}
+
 // checkEnvVarExists checks if the given environment variable exists.
 // If the variable is a secret, any logs will be replaced with a placeholder.
 // If the variable exists the value of the variable is returned, if not then
 // an error is thrown.
 func checkEnvVarExists(ctx context.Context, name string, isSecret bool) (string, error) {
-	val := os.Getenv(name)
+	val := lookupEnvVar(ctx, name, isSecret)
 	if val == "" {
 		return "", fmt.Errorf("%s environment variable is not set", name)
 	}
 
-	log.Ctx(ctx).Debug().Msgf("%s environment variable is set to %s", name, sanitizeValue(val, isSecret))
-
 	return val, nil
 }
 
@@ -0,0 +1,110 @@
+package detect
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// scpLikeURLRegex matches SCP-like git URLs, e.g. git@github.com:owner/repo.git
+var scpLikeURLRegex = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// gitURL contains the parts of a git remote or web URL that are used to
+// determine the platform and project.
+type gitURL struct {
+	// Scheme is the URL scheme, this is empty for SCP-like URLs.
+	Scheme string
+	Host   string
+	// Port is the port of the URL, this is empty if no port is specified.
+	Port string
+	// Path is the path of the URL without any leading or trailing slashes and
+	// without the .git suffix.
+	Path string
+}
+
+// parseGitURL parses a git remote URL or a web URL for a repository. It supports
+// HTTP(S) and SSH URLs, as well as SCP-like URLs such as git@github.com:owner/repo.git.
+func parseGitURL(s string) (gitURL, error) {
+	s = strings.TrimSpace(s)
+
+	if !strings.Contains(s, "://") {
+		m := scpLikeURLRegex.FindStringSubmatch(s)
+		if m == nil {
+			return gitURL{}, fmt.Errorf("Could not parse git URL %s", s)
+		}
+
+		return gitURL{
+			Host: m[1],
+			Path: cleanGitPath(m[2]),
+		}, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return gitURL{}, fmt.Errorf("Could not parse git URL %s", s)
+	}
+
+	if u.Hostname() == "" {
+		return gitURL{}, fmt.Errorf("Could not parse git URL %s", s)
+	}
+
+	return gitURL{
+		Scheme: u.Scheme,
+		Host:   u.Hostname(),
+		Port:   u.Port(),
+		Path:   cleanGitPath(u.Path),
+	}, nil
+}
+
+// cleanGitPath removes any leading or trailing slashes and the .git suffix from the path.
+func cleanGitPath(p string) string {
+	p = strings.Trim(p, "/")
+	p = strings.TrimSuffix(p, ".git")
+	return p
+}
+
+// webURL returns the base web URL of the host, e.g. https://github.example.com.
+// SSH and SCP-like URLs are assumed to be served over HTTPS on the default port.
+func (u gitURL) webURL() string {
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Port != "" {
+		return fmt.Sprintf("%s://%s:%s", u.Scheme, u.Host, u.Port)
+	}
+
+	scheme := u.Scheme
+	if scheme != "http" {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, u.Host)
+}
+
+// platformForHost returns the platform for well-known hosts. It returns an
+// empty string if the platform can't be determined from the host.
+func platformForHost(host string) string {
+	switch strings.ToLower(host) {
+	case "github.com":
+		return "github"
+	case "gitlab.com":
+		return "gitlab"
+	case "bitbucket.org":
+		return "bitbucket"
+	}
+
+	return ""
+}
+
+// pullRequestNumberFromURL returns the pull/merge request number from a pull/merge
+// request web URL, e.g. https://github.com/owner/repo/pull/3. It returns an empty
+// string if the URL does not end in a number.
+func pullRequestNumberFromURL(s string) string {
+	parts := strings.Split(strings.TrimRight(s, "/"), "/")
+	n := parts[len(parts)-1]
+
+	if _, err := strconv.Atoi(n); err != nil {
+		return ""
+	}
+
+	return n
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
 )
@@ -40,9 +41,9 @@ func (d *GitHubActionsDetector) Detect(ctx context.Context, opts DetectOptions)
 		return DetectResult{}, &DetectError{err}
 	}
 
-	apiURL := os.Getenv("GITHUB_API_URL")
+	apiURL := lookupEnvVar(ctx, "GITHUB_API_URL", false)
 
-	eventPath := os.Getenv("GITHUB_EVENT_PATH")
+	eventPath := lookupEnvVar(ctx, "GITHUB_EVENT_PATH", false)
 
 	var event struct {
 		PullRequest struct {
@@ -89,6 +90,18 @@ func (d *GitHubActionsDetector) Detect(ctx context.Context, opts DetectOptions)
 		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
 	}
 
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA = event.PullRequest.Head.SHA
+	}
+
+	var pipelineURL string
+	serverURL := lookupEnvVar(ctx, "GITHUB_SERVER_URL", false)
+	runID := lookupEnvVar(ctx, "GITHUB_RUN_ID", false)
+	if serverURL != "" && runID != "" {
+		pipelineURL = fmt.Sprintf("%s/%s/actions/runs/%s", serverURL, project, runID)
+	}
+
 	return DetectResult{
 		Platform:   "github",
 		Project:    project,
@@ -98,10 +111,12 @@ func (d *GitHubActionsDetector) Detect(ctx context.Context, opts DetectOptions)
 			APIURL: apiURL,
 			Token:  token,
 		},
+		CommitSHA:   commitSHA,
+		PipelineURL: pipelineURL,
 	}, nil
 }
 
 func init() {
 	// Here we register the detectors against the platforms they detect
-	registerDetector([]string{"github"}, &GitHubActionsDetector{})
+	registerDetector("github-actions", []string{"github"}, &GitHubActionsDetector{})
 }
@@ -4,7 +4,6 @@ import (
 	"compost/internal/comment"
 	"context"
 	"errors"
-	"os"
 )
 
 // GitLabCIDetector detects a GitLab CI environment.
@@ -38,13 +37,13 @@ func (d *GitLabCIDetector) Detect(ctx context.Context, opts DetectOptions) (Dete
 		return DetectResult{}, &DetectError{err}
 	}
 
-	serverURL := os.Getenv("CI_SERVER_URL")
+	serverURL := lookupEnvVar(ctx, "CI_SERVER_URL", false)
 
 	var targetType string
 	var targetRef string
 
 	if opts.TargetType == "" || opts.TargetType == "pull-request" {
-		targetRef = os.Getenv("CI_MERGE_REQUEST_IID")
+		targetRef = lookupEnvVar(ctx, "CI_MERGE_REQUEST_IID", false)
 		if targetRef != "" {
 			targetType = "pull-request"
 		}
@@ -62,6 +61,11 @@ func (d *GitLabCIDetector) Detect(ctx context.Context, opts DetectOptions) (Dete
 		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
 	}
 
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA = lookupEnvVar(ctx, "CI_COMMIT_SHA", false)
+	}
+
 	return DetectResult{
 		Platform:   "gitlab",
 		Project:    project,
@@ -71,10 +75,12 @@ func (d *GitLabCIDetector) Detect(ctx context.Context, opts DetectOptions) (Dete
 			ServerURL: serverURL,
 			Token:     token,
 		},
+		CommitSHA:   commitSHA,
+		PipelineURL: lookupEnvVar(ctx, "CI_PIPELINE_URL", false),
 	}, nil
 }
 
 func init() {
 	// Here we register the detectors against the platforms they detect
-	registerDetector([]string{"gitlab"}, &GitLabCIDetector{})
+	registerDetector("gitlab-ci", []string{"gitlab"}, &GitLabCIDetector{})
 }
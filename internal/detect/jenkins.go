@@ -0,0 +1,146 @@
+package detect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// JenkinsDetector detects a Jenkins environment. It supports multibranch
+// pipelines using the GitHub, GitLab or Bitbucket branch source plugins, as well
+// as jobs triggered by the GitLab plugin.
+type JenkinsDetector struct{}
+
+// DisplayName is the display name to use in any logs or output for this detector.
+func (d *JenkinsDetector) DisplayName() string {
+	return "Jenkins"
+}
+
+// Detect checks the environment variables to determine if it is running in
+// a Jenkins environment. If it is it returns a DetectResult, otherwise
+// it throws a DetectError.
+//
+// The platform and project are determined from the change URL if the job is
+// building a pull/merge request, otherwise from the git URL. If the job is running
+// in the context of a pull/merge request it returns a target type of pull-request
+// and the the pull/merge request number as the target ref. Otherwise it returns a
+// target type of commit and the commit SHA.
+func (d *JenkinsDetector) Detect(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	_, err := checkEnvVarExists(ctx, "JENKINS_URL", false)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	info, prNumber, err := d.repoInfo(ctx)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	if opts.Platform != "" && opts.Platform != info.Platform {
+		return DetectResult{}, &DetectError{fmt.Errorf("Repository platform is %s", info.Platform)}
+	}
+
+	extra, err := repoExtra(ctx, opts, info)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	var targetType string
+	var targetRef string
+
+	if opts.TargetType == "" || opts.TargetType == "pull-request" {
+		targetRef = prNumber
+		if targetRef != "" {
+			targetType = "pull-request"
+		}
+	}
+
+	if targetRef == "" && opts.TargetType == "" || opts.TargetType == "commit" {
+		targetType = "commit"
+		targetRef, err = checkEnvVarExists(ctx, "GIT_COMMIT", false)
+		if err != nil {
+			targetRef = lookupEnvVar(ctx, "gitlabMergeRequestLastCommit", false)
+			if targetRef == "" {
+				return DetectResult{}, &DetectError{err}
+			}
+		}
+	}
+
+	if targetRef == "" {
+		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
+	}
+
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA = lookupEnvVar(ctx, "GIT_COMMIT", false)
+		if commitSHA == "" {
+			commitSHA = lookupEnvVar(ctx, "gitlabMergeRequestLastCommit", false)
+		}
+	}
+
+	return DetectResult{
+		Platform:    info.Platform,
+		Project:     info.Project,
+		TargetType:  targetType,
+		TargetRef:   targetRef,
+		Extra:       extra,
+		CommitSHA:   commitSHA,
+		PipelineURL: lookupEnvVar(ctx, "BUILD_URL", false),
+	}, nil
+}
+
+// repoInfo returns the repository info and the pull/merge request number, if any.
+func (d *JenkinsDetector) repoInfo(ctx context.Context) (repoInfo, string, error) {
+	// Branch source plugins set the CHANGE_* variables for pull/merge request builds
+	changeURL, err := checkEnvVarExists(ctx, "CHANGE_URL", false)
+	if err == nil {
+		info, number, err := repoInfoFromPullRequestURL(changeURL)
+		if err != nil {
+			return repoInfo{}, "", err
+		}
+
+		if changeID := lookupEnvVar(ctx, "CHANGE_ID", false); changeID != "" {
+			number = changeID
+		}
+
+		return info, number, nil
+	}
+
+	// The GitLab plugin sets the gitlab* variables for merge request builds
+	gitlabMRNumber := lookupEnvVar(ctx, "gitlabMergeRequestIid", false)
+	if gitlabMRNumber != "" {
+		repoURL, err := checkEnvVarExists(ctx, "gitlabTargetRepoHttpUrl", false)
+		if err != nil {
+			return repoInfo{}, "", err
+		}
+
+		u, err := parseGitURL(repoURL)
+		if err != nil {
+			return repoInfo{}, "", err
+		}
+
+		info, err := newRepoInfo(u, "gitlab")
+		if err != nil {
+			return repoInfo{}, "", err
+		}
+
+		return info, gitlabMRNumber, nil
+	}
+
+	repoURL, err := checkEnvVarExists(ctx, "GIT_URL", false)
+	if err != nil {
+		return repoInfo{}, "", err
+	}
+
+	info, err := repoInfoFromGitURL(repoURL)
+	if err != nil {
+		return repoInfo{}, "", err
+	}
+
+	return info, "", nil
+}
+
+func init() {
+	// Here we register the detectors against the platforms they detect
+	registerDetector("jenkins", []string{"github", "gitlab", "bitbucket", "bitbucket-server"}, &JenkinsDetector{})
+}
@@ -0,0 +1,72 @@
+package detect
+
+import (
+	"compost/internal/comment"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestJenkinsDetectorBitbucketServer(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want DetectResult
+	}{
+		{
+			name: "commit with context path in the git URL",
+			env: map[string]string{
+				"GIT_URL":    "https://git.example.com/ctx/scm/KEY/repo.git",
+				"GIT_COMMIT": "abc123",
+			},
+			want: DetectResult{
+				Platform:   "bitbucket-server",
+				Project:    "KEY/repo",
+				TargetType: "commit",
+				TargetRef:  "abc123",
+				CommitSHA:  "abc123",
+				Extra:      comment.BitbucketServerExtra{ServerURL: "https://git.example.com/ctx", Token: "token"},
+			},
+		},
+		{
+			name: "pull request with context path in the change URL",
+			env: map[string]string{
+				"CHANGE_URL": "https://git.example.com/ctx/projects/KEY/repos/repo/pull-requests/3",
+				"CHANGE_ID":  "3",
+				"GIT_COMMIT": "abc123",
+			},
+			want: DetectResult{
+				Platform:   "bitbucket-server",
+				Project:    "KEY/repo",
+				TargetType: "pull-request",
+				TargetRef:  "3",
+				CommitSHA:  "abc123",
+				Extra:      comment.BitbucketServerExtra{ServerURL: "https://git.example.com/ctx", Token: "token"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"CHANGE_URL", "CHANGE_ID", "gitlabMergeRequestIid", "GIT_URL", "GIT_COMMIT", "BUILD_URL", "BITBUCKET_USERNAME"} {
+				t.Setenv(name, "")
+			}
+
+			t.Setenv("JENKINS_URL", "https://jenkins.example.com")
+			t.Setenv("BITBUCKET_TOKEN", "token")
+
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			got, err := (&JenkinsDetector{}).Detect(context.Background(), DetectOptions{})
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package detect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// LocalGitDetector detects the environment from the local git checkout. It is
+// used as a last resort when no CI environment is detected, e.g. when running
+// on a developer machine.
+type LocalGitDetector struct{}
+
+// DisplayName is the display name to use in any logs or output for this detector.
+func (d *LocalGitDetector) DisplayName() string {
+	return "local git repository"
+}
+
+// Detect inspects the local git checkout to determine the platform and project
+// from the remote URL. If it succeeds it returns a DetectResult, otherwise
+// it throws a DetectError.
+//
+// If there is an open pull/merge request for the current branch it returns a
+// target type of pull-request and the pull/merge request number as the target ref.
+// Otherwise it returns a target type of commit and the SHA of HEAD.
+func (d *LocalGitDetector) Detect(ctx context.Context, opts DetectOptions) (DetectResult, error) {
+	remoteURL, err := gitRemoteURL(ctx)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	log.Ctx(ctx).Debug().Msgf("git remote URL is %s", remoteURL)
+
+	info, err := repoInfoFromGitURL(remoteURL)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	if opts.Platform != "" && opts.Platform != info.Platform {
+		return DetectResult{}, &DetectError{fmt.Errorf("Repository platform is %s", info.Platform)}
+	}
+
+	token, err := repoToken(ctx, opts, info)
+	if err != nil {
+		token, err = cliConfigToken(ctx, info)
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+	}
+
+	extra, err := newRepoExtra(info, token)
+	if err != nil {
+		return DetectResult{}, &DetectError{err}
+	}
+
+	var targetType string
+	var targetRef string
+
+	if opts.TargetType == "" || opts.TargetType == "pull-request" {
+		branch, err := runGit(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+
+		log.Ctx(ctx).Debug().Msgf("git branch is %s", branch)
+
+		if branch != "HEAD" {
+			// Falling back to a commit comment here would post to the wrong target
+			targetRef, err = findOpenPullRequest(ctx, info, token, branch)
+			if err != nil {
+				return DetectResult{}, fmt.Errorf("Could not find an open pull request for branch %s: %w", branch, err)
+			}
+		}
+
+		if targetRef != "" {
+			targetType = "pull-request"
+		}
+	}
+
+	if targetRef == "" && opts.TargetType == "" || opts.TargetType == "commit" {
+		targetType = "commit"
+		targetRef, err = runGit(ctx, "rev-parse", "HEAD")
+		if err != nil {
+			return DetectResult{}, &DetectError{err}
+		}
+	}
+
+	if targetRef == "" {
+		return DetectResult{}, &DetectError{errors.New("Could not determine target ref")}
+	}
+
+	commitSHA := targetRef
+	if targetType == "pull-request" {
+		commitSHA, _ = runGit(ctx, "rev-parse", "HEAD")
+	}
+
+	return DetectResult{
+		Platform:   info.Platform,
+		Project:    info.Project,
+		TargetType: targetType,
+		TargetRef:  targetRef,
+		Extra:      extra,
+		CommitSHA:  commitSHA,
+	}, nil
+}
+
+// runGit runs a git command in the current directory and returns the trimmed output.
+func runGit(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("Error running git %s: %w", strings.Join(args, " "), err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitRemoteURL returns the URL of the origin remote, or the first remote if
+// there is no origin remote.
+func gitRemoteURL(ctx context.Context) (string, error) {
+	remoteURL, err := runGit(ctx, "remote", "get-url", "origin")
+	if err == nil && remoteURL != "" {
+		return remoteURL, nil
+	}
+
+	remotes, err := runGit(ctx, "remote")
+	if err != nil {
+		return "", err
+	}
+
+	if remotes == "" {
+		return "", errors.New("git repository has no remotes")
+	}
+
+	return runGit(ctx, "remote", "get-url", strings.Split(remotes, "\n")[0])
+}
+
+// cliConfigToken reads the token for the repository's host from the config file of
+// the platform's CLI, i.e. gh for GitHub and glab for GitLab.
+func cliConfigToken(ctx context.Context, info repoInfo) (string, error) {
+	var path string
+	var key string
+
+	switch info.Platform {
+	case "github":
+		dir := os.Getenv("GH_CONFIG_DIR")
+		if dir == "" {
+			dir = filepath.Join(userConfigDir(), "gh")
+		}
+		path = filepath.Join(dir, "hosts.yml")
+		key = "oauth_token"
+
+		if token := lookupEnvVar(ctx, "GH_TOKEN", true); token != "" {
+			return token, nil
+		}
+	case "gitlab":
+		dir := os.Getenv("GLAB_CONFIG_DIR")
+		if dir == "" {
+			dir = filepath.Join(userConfigDir(), "glab-cli")
+		}
+		path = filepath.Join(dir, "config.yml")
+		key = "token"
+	default:
+		return "", fmt.Errorf("No CLI config is supported for platform %s", info.Platform)
+	}
+
+	token, err := readYAMLHostValue(path, info.Host, key)
+	if err != nil {
+		return "", fmt.Errorf("Could not read token from %s: %w", path, err)
+	}
+
+	if token == "" {
+		return "", fmt.Errorf("No token found for %s in %s", info.Host, path)
+	}
+
+	log.Ctx(ctx).Debug().Msgf("Using token for %s from %s", info.Host, path)
+
+	return token, nil
+}
+
+// userConfigDir returns the directory used for CLI config files. The gh and glab CLIs
+// use XDG_CONFIG_HOME or ~/.config on all operating systems.
+func userConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config")
+}
+
+// readYAMLHostValue reads the value of the key nested under the host key in a
+// CLI config YAML file, e.g. the oauth_token in the gh hosts.yml file:
+//
+//	github.com:
+//	    oauth_token: abc
+//
+// The host can also be nested under a hosts key, as in the glab config.yml file.
+func readYAMLHostValue(path string, host string, key string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var config struct {
+		Hosts map[string]map[string]interface{} `yaml:"hosts"`
+	}
+
+	err = yaml.Unmarshal(b, &config)
+	if err != nil {
+		return "", err
+	}
+
+	var hosts map[string]map[string]interface{}
+
+	// The gh hosts.yml file has the hosts at the top level
+	err = yaml.Unmarshal(b, &hosts)
+	if err != nil || hosts[host] == nil {
+		hosts = config.Hosts
+	}
+
+	val, ok := hosts[host][key]
+	if !ok || val == nil {
+		return "", nil
+	}
+
+	return fmt.Sprint(val), nil
+}
+
+// findOpenPullRequest calls the platform API to find the number of the open pull/merge
+// request for the branch. It returns an empty string if there is no open pull request.
+func findOpenPullRequest(ctx context.Context, info repoInfo, token string, branch string) (string, error) {
+	var reqURL string
+
+	switch info.Platform {
+	case "github":
+		apiURL := "https://api.github.com"
+		if info.ServerURL != "" {
+			apiURL = fmt.Sprintf("%s/api/v3", info.ServerURL)
+		}
+		owner := strings.SplitN(info.Project, "/", 2)[0]
+		reqURL = fmt.Sprintf("%s/repos/%s/pulls?state=open&head=%s", apiURL, info.Project, url.QueryEscape(owner+":"+branch))
+
+		var resData []struct {
+			Number int `json:"number"`
+		}
+		err := getJSON(ctx, reqURL, fmt.Sprintf("Bearer %s", token), &resData)
+		if err != nil || len(resData) == 0 {
+			return "", err
+		}
+		return strconv.Itoa(resData[0].Number), nil
+	case "gitlab":
+		serverURL := "https://gitlab.com"
+		if info.ServerURL != "" {
+			serverURL = info.ServerURL
+		}
+		reqURL = fmt.Sprintf("%s/api/v4/projects/%s/merge_requests?state=opened&source_branch=%s", serverURL, url.PathEscape(info.Project), url.QueryEscape(branch))
+
+		var resData []struct {
+			IID int `json:"iid"`
+		}
+		err := getJSON(ctx, reqURL, fmt.Sprintf("Bearer %s", token), &resData)
+		if err != nil || len(resData) == 0 {
+			return "", err
+		}
+		return strconv.Itoa(resData[0].IID), nil
+	case "bitbucket":
+		q := fmt.Sprintf(`source.branch.name="%s" AND state="OPEN"`, branch)
+		reqURL = fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/pullrequests?q=%s", info.Project, url.QueryEscape(q))
+
+		var resData struct {
+			Values []struct {
+				ID int `json:"id"`
+			} `json:"values"`
+		}
+		err := getJSON(ctx, reqURL, bitbucketAuthHeader(token), &resData)
+		if err != nil || len(resData.Values) == 0 {
+			return "", err
+		}
+		return strconv.Itoa(resData.Values[0].ID), nil
+	case "bitbucket-server":
+		parts := strings.SplitN(info.Project, "/", 2)
+		reqURL = fmt.Sprintf(
+			"%s/rest/api/1.0/projects/%s/repos/%s/pull-requests?state=OPEN&direction=OUTGOING&at=%s",
+			info.ServerURL, url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.QueryEscape("refs/heads/"+branch),
+		)
+
+		var resData struct {
+			Values []struct {
+				ID int `json:"id"`
+			} `json:"values"`
+		}
+		err := getJSON(ctx, reqURL, bitbucketAuthHeader(token), &resData)
+		if err != nil || len(resData.Values) == 0 {
+			return "", err
+		}
+		return strconv.Itoa(resData.Values[0].ID), nil
+	}
+
+	return "", fmt.Errorf("Platform %s is not supported", info.Platform)
+}
+
+// bitbucketAuthHeader returns the Authorization header for a Bitbucket token.
+// App passwords in the form username:app-password use basic auth, access tokens
+// use bearer auth.
+func bitbucketAuthHeader(token string) string {
+	parts := strings.SplitN(token, ":", 2)
+	if len(parts) == 2 {
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(parts[0], parts[1])
+		return req.Header.Get("Authorization")
+	}
+
+	return fmt.Sprintf("Bearer %s", token)
+}
+
+// getJSON sends a GET request to the URL and unmarshals the JSON response into resData.
+func getJSON(ctx context.Context, reqURL string, authHeader string, resData interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("Error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", authHeader)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected response: %s", res.Status)
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading response body: %w", err)
+	}
+
+	return json.Unmarshal(resBody, resData)
+}
+
+func init() {
+	// The local git detector is registered as a fallback so it is only used when no CI environment is detected
+	registerFallbackDetector("local-git", []string{"github", "gitlab", "bitbucket", "bitbucket-server"}, &LocalGitDetector{})
+}
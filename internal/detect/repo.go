@@ -0,0 +1,219 @@
+package detect
+
+import (
+	"compost/internal/comment"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// repoInfo contains the platform and project of a repository derived from its URL.
+type repoInfo struct {
+	Platform string
+	Project  string
+	Host     string
+	// ServerURL is the web URL of a self-hosted server. It is empty for
+	// repositories hosted on github.com, gitlab.com or bitbucket.org.
+	ServerURL string
+}
+
+// repoInfoFromGitURL returns the repoInfo for a git remote URL. The platform is
+// determined from the host, with self-hosted servers detected if the host name
+// contains the name of the platform, e.g. gitlab.example.com, or for Bitbucket
+// Server if the URL is an HTTP clone URL in the form /scm/project/repo.
+func repoInfoFromGitURL(s string) (repoInfo, error) {
+	u, err := parseGitURL(s)
+	if err != nil {
+		return repoInfo{}, err
+	}
+
+	platform := platformForHost(u.Host)
+
+	if platform == "" {
+		host := strings.ToLower(u.Host)
+		_, _, isBitbucketServerClone := bitbucketServerClonePath(u.Path)
+
+		switch {
+		case strings.Contains(host, "github"):
+			platform = "github"
+		case strings.Contains(host, "gitlab"):
+			platform = "gitlab"
+		case strings.Contains(host, "bitbucket"), isBitbucketServerClone:
+			platform = "bitbucket-server"
+		default:
+			return repoInfo{}, fmt.Errorf("Could not determine the platform for host %s", u.Host)
+		}
+	}
+
+	return newRepoInfo(u, platform)
+}
+
+// newRepoInfo returns the repoInfo for a git remote URL on the given platform.
+func newRepoInfo(u gitURL, platform string) (repoInfo, error) {
+	path := u.Path
+	contextPath := ""
+
+	// Bitbucket Server HTTP clone URLs are in the form /scm/project/repo, after the
+	// context path the server is served under, if any
+	if platform == "bitbucket-server" {
+		if serverPath, project, ok := bitbucketServerClonePath(path); ok {
+			contextPath = serverPath
+			path = project
+		}
+	}
+
+	if !strings.Contains(path, "/") {
+		return repoInfo{}, fmt.Errorf("Could not determine the project from URL path %s", u.Path)
+	}
+
+	var serverURL string
+	if platformForHost(u.Host) == "" {
+		serverURL = u.webURL()
+
+		if contextPath != "" {
+			serverURL = fmt.Sprintf("%s/%s", serverURL, contextPath)
+		}
+	}
+
+	return repoInfo{
+		Platform:  platform,
+		Project:   path,
+		Host:      u.Host,
+		ServerURL: serverURL,
+	}, nil
+}
+
+// repoInfoFromPullRequestURL returns the repoInfo and the pull/merge request number
+// for a pull/merge request web URL. The platform is determined from the format of
+// the URL path, so this works with self-hosted servers with any host name.
+func repoInfoFromPullRequestURL(s string) (repoInfo, string, error) {
+	u, err := parseGitURL(s)
+	if err != nil {
+		return repoInfo{}, "", err
+	}
+
+	number := pullRequestNumberFromURL(s)
+	if number == "" {
+		return repoInfo{}, "", fmt.Errorf("Could not determine the pull request number from URL %s", s)
+	}
+
+	var platform string
+	var project string
+
+	// Bitbucket Server may be served under a context path, which is kept in the server URL
+	serverPath, bitbucketServerProject, isBitbucketServer := bitbucketServerPullRequestPath(u.Path)
+
+	switch {
+	case strings.Contains(u.Path, "/-/merge_requests/"):
+		platform = "gitlab"
+		project = strings.Split(u.Path, "/-/merge_requests/")[0]
+	case strings.Contains(u.Path, "/merge_requests/"):
+		platform = "gitlab"
+		project = strings.Split(u.Path, "/merge_requests/")[0]
+	case strings.Contains(u.Path, "/pull/"):
+		platform = "github"
+		project = strings.Split(u.Path, "/pull/")[0]
+	case isBitbucketServer:
+		platform = "bitbucket-server"
+		project = bitbucketServerProject
+	case strings.Contains(u.Path, "/pull-requests/"):
+		platform = "bitbucket"
+		project = strings.Split(u.Path, "/pull-requests/")[0]
+	default:
+		return repoInfo{}, "", fmt.Errorf("Could not determine the platform from URL %s", s)
+	}
+
+	info, err := newRepoInfo(gitURL{Scheme: u.Scheme, Host: u.Host, Port: u.Port, Path: project}, platform)
+	if err != nil {
+		return repoInfo{}, "", err
+	}
+
+	if platform == "bitbucket-server" && serverPath != "" {
+		info.ServerURL = fmt.Sprintf("%s/%s", info.ServerURL, serverPath)
+	}
+
+	return info, number, nil
+}
+
+// bitbucketServerPullRequestPath finds a Bitbucket Server pull request path in the form
+// projects/KEY/repos/repo/pull-requests/1 anywhere in the URL path. It returns the
+// context path of the server before it and the project in the form KEY/repo.
+func bitbucketServerPullRequestPath(path string) (string, string, bool) {
+	parts := strings.Split(path, "/")
+
+	for i := 0; i+5 < len(parts); i++ {
+		if parts[i] == "projects" && parts[i+2] == "repos" && parts[i+4] == "pull-requests" {
+			return strings.Join(parts[:i], "/"), fmt.Sprintf("%s/%s", parts[i+1], parts[i+3]), true
+		}
+	}
+
+	return "", "", false
+}
+
+// bitbucketServerClonePath finds a Bitbucket Server HTTP clone path in the form
+// scm/KEY/repo at the end of the URL path. It returns the context path of the server
+// before it and the project in the form KEY/repo.
+func bitbucketServerClonePath(path string) (string, string, bool) {
+	parts := strings.Split(path, "/")
+
+	i := len(parts) - 3
+	if i < 0 || parts[i] != "scm" {
+		return "", "", false
+	}
+
+	return strings.Join(parts[:i], "/"), fmt.Sprintf("%s/%s", parts[i+1], parts[i+2]), true
+}
+
+// repoToken returns the token for the repository's platform. The token is read from
+// the platform's default token environment variable.
+func repoToken(ctx context.Context, opts DetectOptions, info repoInfo) (string, error) {
+	switch info.Platform {
+	case "github":
+		return checkEnvVarExists(ctx, "GITHUB_TOKEN", true)
+	case "gitlab":
+		return checkEnvVarExists(ctx, "GITLAB_TOKEN", true)
+	case "bitbucket", "bitbucket-server":
+		return bitbucketToken(ctx, opts)
+	}
+
+	return "", fmt.Errorf("Platform %s is not supported", info.Platform)
+}
+
+// repoExtra returns the extra inputs for the comment handler of the repository's platform.
+// The token is read from the platform's default token environment variable.
+func repoExtra(ctx context.Context, opts DetectOptions, info repoInfo) (interface{}, error) {
+	token, err := repoToken(ctx, opts, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRepoExtra(info, token)
+}
+
+// newRepoExtra returns the extra inputs for the comment handler of the repository's platform
+// using the given token.
+func newRepoExtra(info repoInfo, token string) (interface{}, error) {
+	switch info.Platform {
+	case "github":
+		return comment.GitHubExtra{
+			APIURL: info.ServerURL,
+			Token:  token,
+		}, nil
+	case "gitlab":
+		return comment.GitLabExtra{
+			ServerURL: info.ServerURL,
+			Token:     token,
+		}, nil
+	case "bitbucket":
+		return comment.BitbucketExtra{
+			Token: token,
+		}, nil
+	case "bitbucket-server":
+		return comment.BitbucketServerExtra{
+			ServerURL: info.ServerURL,
+			Token:     token,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Platform %s is not supported", info.Platform)
+}
@@ -0,0 +1,90 @@
+package detect
+
+import "testing"
+
+func TestRepoInfoFromGitURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want repoInfo
+	}{
+		{
+			url:  "git@github.com:owner/repo.git",
+			want: repoInfo{Platform: "github", Project: "owner/repo", Host: "github.com"},
+		},
+		{
+			url:  "https://gitlab.example.com/group/subgroup/repo.git",
+			want: repoInfo{Platform: "gitlab", Project: "group/subgroup/repo", Host: "gitlab.example.com", ServerURL: "https://gitlab.example.com"},
+		},
+		{
+			url:  "https://bitbucket.example.com/scm/KEY/repo.git",
+			want: repoInfo{Platform: "bitbucket-server", Project: "KEY/repo", Host: "bitbucket.example.com", ServerURL: "https://bitbucket.example.com"},
+		},
+		{
+			url:  "ssh://git@bitbucket.example.com:7999/KEY/repo.git",
+			want: repoInfo{Platform: "bitbucket-server", Project: "KEY/repo", Host: "bitbucket.example.com", ServerURL: "https://bitbucket.example.com"},
+		},
+		{
+			url:  "https://git.example.com:8443/ctx/scm/KEY/repo.git",
+			want: repoInfo{Platform: "bitbucket-server", Project: "KEY/repo", Host: "git.example.com", ServerURL: "https://git.example.com:8443/ctx"},
+		},
+		{
+			url:  "https://bitbucket.example.com/tools/bitbucket/scm/KEY/repo",
+			want: repoInfo{Platform: "bitbucket-server", Project: "KEY/repo", Host: "bitbucket.example.com", ServerURL: "https://bitbucket.example.com/tools/bitbucket"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := repoInfoFromGitURL(tt.url)
+			if err != nil {
+				t.Fatalf("repoInfoFromGitURL() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("repoInfoFromGitURL() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoInfoFromPullRequestURL(t *testing.T) {
+	tests := []struct {
+		url        string
+		want       repoInfo
+		wantNumber string
+	}{
+		{
+			url:        "https://github.com/owner/repo/pull/3",
+			want:       repoInfo{Platform: "github", Project: "owner/repo", Host: "github.com"},
+			wantNumber: "3",
+		},
+		{
+			url:        "https://gitlab.example.com/group/repo/-/merge_requests/4",
+			want:       repoInfo{Platform: "gitlab", Project: "group/repo", Host: "gitlab.example.com", ServerURL: "https://gitlab.example.com"},
+			wantNumber: "4",
+		},
+		{
+			url:        "https://bitbucket.org/workspace/repo/pull-requests/5",
+			want:       repoInfo{Platform: "bitbucket", Project: "workspace/repo", Host: "bitbucket.org"},
+			wantNumber: "5",
+		},
+		{
+			url:        "https://git.example.com/ctx/projects/KEY/repos/repo/pull-requests/6",
+			want:       repoInfo{Platform: "bitbucket-server", Project: "KEY/repo", Host: "git.example.com", ServerURL: "https://git.example.com/ctx"},
+			wantNumber: "6",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, number, err := repoInfoFromPullRequestURL(tt.url)
+			if err != nil {
+				t.Fatalf("repoInfoFromPullRequestURL() error = %v", err)
+			}
+
+			if got != tt.want || number != tt.wantNumber {
+				t.Errorf("repoInfoFromPullRequestURL() = %+v, %s, want %+v, %s", got, number, tt.want, tt.wantNumber)
+			}
+		})
+	}
+}
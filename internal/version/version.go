@@ -0,0 +1,4 @@
+package version
+
+// Version is the version of compost. It is set at build time using ldflags.
+var Version = "dev"